@@ -1,23 +1,45 @@
+import base64
+import glob
+import hashlib
 import io
 import os
+import queue
 import re
+import subprocess
+import threading
+import time
+import uuid
 
+import yaml
 from PIL import Image
-from flask import Flask, Response, request
+from flask import Flask, Response, jsonify, request
 
 app = Flask(__name__)
 
+# provenance of painting, soul saves it with art (X-Artist-Version and X-Model-Checksum headers)
+ARTIST_VERSION = 'invokeai-2.3.0/1'
+INVOKEAI_ROOT = '/home/artchitector/invoke-ai/invokeai_v2.3.0'
+MODELS_CONFIG = INVOKEAI_ROOT + '/configs/models.yaml'
+INTERMEDIATES_DIR = INVOKEAI_ROOT + '/outputs/intermediates'
+
+# jobs (see soul/core/artist/engine/job.go): one job is painted at a time, finished jobs are forgotten after JOB_TTL
+JOB_TTL = 600  # seconds
+PREVIEW_EVERY = 5  # steps between saved intermediates
+PREVIEW_SIZE = (160, 240)
+
+model_checksums = {}  # weights path -> sha256, weights are big, so they are hashed once
+
+jobs = {}
+jobs_lock = threading.Lock()
+jobs_queue = queue.Queue()
+worker = None
+
 
 @app.route('/painting', methods=['POST'])
 def painting():
-    print('tags: ' + request.form['tags'])
-    print('seed: ' + request.form['seed'])
-    print('width: ' + request.form['width'])
-    print('height: ' + request.form['height'])
-    print('steps: ' + request.form['steps'])
-    print('version: ' + request.form['version'])
+    printForm(request.form)
 
-    filename = getPaintingFromInvokeAIFilename(request.form['version'])
+    filename = getPaintingFromInvokeAIFilename(request.form)
 
     im = Image.open(filename)
     print(im.format, im.size, im.mode)
@@ -25,25 +47,244 @@ def painting():
     img_byte_arr = io.BytesIO()
     im.save(img_byte_arr, format="PNG")
 
-    return Response(img_byte_arr.getvalue(), content_type="image/png")
+    return paintingResponse(img_byte_arr.getvalue(), request.form.get('model'))
 
 
-def getPaintingFromInvokeAIFilename(version):
-    prepareFileForInvokeAI(version)
+@app.route('/jobs', methods=['POST'])
+def submitJob():
+    printForm(request.form)
+    forgetOldJobs()
 
-    filename = None
-    cmd = 'INVOKEAI_ROOT=/home/artchitector/invoke-ai/invokeai_v2.3.0/ ' \
-          + '/home/artchitector/invoke-ai/invokeai_v2.3.0/.venv/bin/python /home/artchitector/invoke-ai/invokeai_v2.3.0/.venv/bin/invoke.py ' \
-          + '--from_file "/home/artchitector/invoke-ai/invokeai_v2.3.0/list.txt"'
-    pattern = re.compile(".*(\/home\/artchitector\/invoke-ai\/invokeai_v2.3.0\/outputs\/[0-9\.]+png).*")
+    job = {
+        'id': uuid.uuid4().hex,
+        'status': 'queued',
+        'step': 0,
+        'total_steps': int(request.form['steps']),
+        'percent': 0.0,
+        'error': '',
+        'form': request.form.to_dict(),
+        'preview': request.form.get('preview') == 'true',
+        'started': 0,
+        'finished': 0,
+        'process': None,
+        'filename': None,
+    }
+    with jobs_lock:
+        jobs[job['id']] = job
+        startWorker()
+    jobs_queue.put(job['id'])
+    print(f"Job {job['id']} is queued")
+    return jsonify(jobStatus(job))
+
+
+@app.route('/jobs/<job_id>', methods=['GET'])
+def getJob(job_id):
+    with jobs_lock:
+        job = jobs.get(job_id)
+        if job is None:
+            return jsonify({'error': 'job not found'}), 404
+        status = jobStatus(job)
+    if request.args.get('preview') == 'true' and job['preview'] and status['status'] == 'running':
+        status['preview'] = lastIntermediate(job['started'])  # file is read without lock, worker is not stopped
+    return jsonify(status)
+
+
+@app.route('/jobs/<job_id>/result', methods=['GET'])
+def getJobResult(job_id):
+    with jobs_lock:
+        job = jobs.get(job_id)
+        if job is None:
+            return jsonify({'error': 'job not found'}), 404
+        if job['status'] != 'done':
+            return jsonify({'error': f"job is {job['status']}"}), 409
+        filename, model = job['filename'], job['form'].get('model')
+
+    img_byte_arr = io.BytesIO()
+    Image.open(filename).save(img_byte_arr, format="PNG")
+    return paintingResponse(img_byte_arr.getvalue(), model)
+
+
+@app.route('/jobs/<job_id>', methods=['DELETE'])
+def cancelJob(job_id):
+    with jobs_lock:
+        job = jobs.get(job_id)
+        if job is None:
+            return jsonify({'error': 'job not found'}), 404
+        if job['status'] in ['queued', 'running']:
+            job['status'] = 'cancelled'
+            job['finished'] = time.time()
+            if job['process'] is not None:
+                job['process'].terminate()
+            print(f"Job {job_id} is cancelled")
+        return jsonify(jobStatus(job))
+
+
+def startWorker():
+    # worker is started with first job, so it is not started in reloader process of flask debug mode
+    global worker
+    if worker is None:
+        worker = threading.Thread(target=runJobs, daemon=True)
+        worker.start()
+
+
+def runJobs():
+    while True:
+        job_id = jobs_queue.get()
+        with jobs_lock:
+            job = jobs.get(job_id)
+            if job is None or job['status'] != 'queued':
+                continue  # cancelled while it was in queue
+            job['status'] = 'running'
+            job['started'] = time.time()
+        try:
+            runJob(job)
+        except Exception as e:
+            print(f"Job {job_id} failed: {e}")
+            with jobs_lock:
+                if job['status'] == 'running':
+                    job['status'] = 'failed'
+                    job['error'] = str(e)
+        with jobs_lock:
+            job['process'] = None
+            job['finished'] = time.time()
+        if job['preview']:
+            removeIntermediates(job['started'])
+
+
+def runJob(job):
+    list_filename = f"{INVOKEAI_ROOT}/list-{job['id']}.txt"
+    prepareFileForInvokeAI(job['form'], list_filename, PREVIEW_EVERY if job['preview'] else 0)
+    try:
+        # without shell, so terminate stops invoke.py itself
+        process = subprocess.Popen([f'{INVOKEAI_ROOT}/.venv/bin/python', f'{INVOKEAI_ROOT}/.venv/bin/invoke.py',
+                                    '--from_file', list_filename],
+                                   env=dict(os.environ, INVOKEAI_ROOT=INVOKEAI_ROOT + '/'),
+                                   stdout=subprocess.PIPE, stderr=subprocess.STDOUT, text=True)
+        with jobs_lock:
+            job['process'] = process
+            if job['status'] == 'cancelled':
+                process.terminate()  # cancelled before process was started
+
+        # tqdm redraws progress with \r, text mode splits lines by \r too
+        filename = None
+        step_pattern = re.compile(r".*\b(\d+)/(\d+) \[")
+        for line in iter(process.stdout.readline, ''):
+            match = step_pattern.match(line)
+            if match is not None and int(match.groups()[1]) == job['total_steps']:
+                step = int(match.groups()[0])
+                with jobs_lock:
+                    job['step'] = step
+                    job['percent'] = 100.0 * step / job['total_steps']
+            filename = findPaintingFilename(line) or filename
+        process.wait()
+    finally:
+        os.remove(list_filename)
+
+    with jobs_lock:
+        if job['status'] != 'running':
+            return  # cancelled
+        if process.returncode != 0 or filename is None:
+            job['status'] = 'failed'
+            job['error'] = f"invoke.py exited with {process.returncode}, filename is {filename}"
+            return
+        job['status'] = 'done'
+        job['step'] = job['total_steps']
+        job['percent'] = 100.0
+        job['filename'] = filename
+    print(f"Job {job['id']} is done: {filename}")
+
+
+def jobStatus(job):
+    return {
+        'id': job['id'],
+        'status': job['status'],
+        'step': job['step'],
+        'total_steps': job['total_steps'],
+        'percent': job['percent'],
+        'preview': '',
+        'error': job['error'],
+    }
 
-    ret = os.popen(cmd)
+
+def lastIntermediate(since):
+    # InvokeAI saves every PREVIEW_EVERY step into outputs/intermediates, the newest one of current job is preview
+    files = [f for f in glob.glob(INTERMEDIATES_DIR + '/**/*.png', recursive=True) if os.path.getmtime(f) >= since]
+    if len(files) == 0:
+        return ''
+    try:
+        im = Image.open(max(files, key=os.path.getmtime)).convert('RGB')
+        im.thumbnail(PREVIEW_SIZE)
+        img_byte_arr = io.BytesIO()
+        im.save(img_byte_arr, format="JPEG", quality=70)
+        return base64.b64encode(img_byte_arr.getvalue()).decode('ascii')
+    except Exception as e:
+        print(f"Failed to read intermediate: {e}")  # file can be written right now
+        return ''
+
+
+def removeIntermediates(since):
+    for f in glob.glob(INTERMEDIATES_DIR + '/**/*.png', recursive=True):
+        if os.path.getmtime(f) >= since:
+            os.remove(f)
+
+
+def forgetOldJobs():
+    with jobs_lock:
+        for job_id in [job_id for job_id, job in jobs.items() if job['finished'] and time.time() - job['finished'] > JOB_TTL]:
+            del jobs[job_id]
+
+
+def printForm(form):
+    print('tags: ' + form['tags'])
+    print('seed: ' + form['seed'])
+    print('width: ' + form['width'])
+    print('height: ' + form['height'])
+    print('steps: ' + form['steps'])
+    print('version: ' + form['version'])
+    # optional params of dictionary version, InvokeAI defaults without them
+    for param in ['negative_prompt', 'model', 'sampler', 'cfg_scale']:
+        if form.get(param):
+            print(f'{param}: ' + form[param])
+
+
+def paintingResponse(data, model):
+    response = Response(data, content_type="image/png")
+    response.headers['X-Artist-Version'] = ARTIST_VERSION
+    try:
+        response.headers['X-Model-Checksum'] = modelChecksum(model)
+    except Exception as e:
+        # painting is good without checksum, soul saves empty checksum as unknown
+        print(f"Failed to get model checksum: {e}")
+    return response
+
+
+def modelChecksum(model):
+    with open(MODELS_CONFIG) as config_file:
+        models = yaml.safe_load(config_file)
+    if not model:
+        model = next(name for name, config in models.items() if config.get('default'))
+    weights = models[model]['weights']
+    if not os.path.isabs(weights):
+        weights = os.path.join(INVOKEAI_ROOT, weights)
+
+    if weights not in model_checksums:
+        sha = hashlib.sha256()
+        with open(weights, 'rb') as weights_file:
+            for chunk in iter(lambda: weights_file.read(1024 * 1024), b''):
+                sha.update(chunk)
+        model_checksums[weights] = sha.hexdigest()
+    return model_checksums[weights]
+
+
+def getPaintingFromInvokeAIFilename(form):
+    list_filename = INVOKEAI_ROOT + '/list.txt'
+    prepareFileForInvokeAI(form, list_filename, 0)
+
+    filename = None
+    ret = os.popen(invokeCmd(list_filename))
     lines = ret.readlines()
     for line in lines:
-        match = pattern.match(line)
-        if match is not None:
-            filename = match.groups()[0]
-            print(f"Found filename: {filename}")
+        filename = findPaintingFilename(line) or filename
 
     if filename is not None:
         return filename
@@ -51,22 +292,53 @@ def getPaintingFromInvokeAIFilename(version):
         raise Exception("filename not found")
 
 
-def prepareFileForInvokeAI(version):
-    tags = request.form['tags']
-    seed = request.form['seed']
-    width = request.form['width']
-    height = request.form['height']
-    steps = request.form['steps']
-    upscale = request.form['upscale']
+def invokeCmd(list_filename):
+    return f'INVOKEAI_ROOT={INVOKEAI_ROOT}/ ' \
+           + f'{INVOKEAI_ROOT}/.venv/bin/python {INVOKEAI_ROOT}/.venv/bin/invoke.py ' \
+           + f'--from_file "{list_filename}"'
+
 
+def findPaintingFilename(line):
+    pattern = re.compile(".*(\/home\/artchitector\/invoke-ai\/invokeai_v2.3.0\/outputs\/[0-9\.]+png).*")
+    match = pattern.match(line)
+    if match is None:
+        return None
+    filename = match.groups()[0]
+    print(f"Found filename: {filename}")
+    return filename
+
+
+def prepareFileForInvokeAI(form, filename, save_intermediates):
     lines = []
-    lines.append(f'{tags} -S{seed} -W{width} -H{height} -s{steps} -U{upscale}')
-    filename = "/home/artchitector/invoke-ai/invokeai_v2.3.0/list.txt"
+    model = form.get('model')
+    if model:
+        # model of dictionary version (name from InvokeAI models.yaml)
+        lines.append(f'!switch {model}')
+    command = invokeCommand(form)
+    if save_intermediates:
+        command += f' --save_intermediates {save_intermediates}'
+    lines.append(command)
     with open(filename, "w") as text_file:
-        for line in lines:
-            text_file.write(line)
+        text_file.write("\n".join(lines) + "\n")
     text_file.close()
 
 
+def invokeCommand(form):
+    prompt = form['tags']
+    negative_prompt = form.get('negative_prompt')
+    if negative_prompt:
+        # InvokeAI takes words in square brackets as negative prompt
+        prompt += f' [{negative_prompt}]'
+
+    command = f'{prompt} -S{form["seed"]} -W{form["width"]} -H{form["height"]} -s{form["steps"]} -U{form["upscale"]}'
+    sampler = form.get('sampler')
+    if sampler:
+        command += f' -A{sampler}'
+    cfg_scale = form.get('cfg_scale')
+    if cfg_scale:
+        command += f' -C{cfg_scale}'
+    return command
+
+
 if __name__ == '__main__':
     app.run(host='0.0.0.0', port=8083, debug=True)
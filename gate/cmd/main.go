@@ -34,6 +34,8 @@ func main() {
 	selectionRepo := repository2.NewSelectionRepository(res.GetDB())
 	likeRepo := repository2.NewLikeRepository(res.GetDB())
 	unityRepo := repository2.NewUnityRepository(res.GetDB())
+	decisionRepo := repository2.NewDecisionRepository(res.GetDB())
+	tagStatsRepo := repository2.NewTagStatsRepository(res.GetDB())
 
 	// cache
 	cache := cache2.NewCache(res.GetRedis())
@@ -72,11 +74,15 @@ func main() {
 	authS := handler.NewAuthService(res.GetEnv().JWTSecret, res.GetEnv().AllowFakeAuth)
 	cardHandler := handler.NewCardHandler(artsRepo, cache, likeRepo, authS)
 	selectionHander := handler.NewSelectionHandler(selectionRepo)
-	prayHandler := handler.NewPrayHandler(prayRepo)
+	prayHandler := handler.NewPrayHandler(prayRepo, artsRepo)
 	lh := handler.NewLoginHandler(res.GetEnv().TelegramABotToken, res.GetEnv().JWTSecret, res.GetEnv().ArtchitectHost)
 	llh := handler.NewLikeHandler(likeRepo, artsRepo, authS, enhotter, artchitectBot, uint(res.GetEnv().ChatIDArtchitector), res.GetEnv().SendToInfiniteOnLike)
 	uh := handler.NewUnityHandler(unityRepo, artsRepo)
 	ih := handler.NewImageHandler(mmr)
+	dh := handler.NewDecisionHandler(decisionRepo, artsRepo)
+	ph := handler.NewProvenanceHandler(artsRepo, res.GetEnv().ProvenanceSecret)
+	tsh := handler.NewTagStatsHandler(tagStatsRepo)
+	sh := handler.NewSearchHandler(artsRepo)
 
 	go func() {
 		r := gin.Default()
@@ -92,6 +98,15 @@ func main() {
 		r.GET("/last_paintings/:quantity", lastCardsHandler.Handle)
 		r.GET("/lottery/:lastN", lotteryHandler.HandleLast)
 		r.GET("/card/:id", cardHandler.Handle)
+		r.GET("/card/:id/decisions", dh.Handle)
+		r.GET("/card/:id/provenance", ph.Handle)
+		r.POST("/provenance/verify", ph.HandleVerify)
+		r.GET("/search", sh.Handle)
+		r.GET("/stats/tags", tsh.HandleFrequency)
+		r.GET("/stats/tags/versions", tsh.HandleVersions)
+		r.GET("/stats/tags/pairs", tsh.HandlePairs)
+		r.GET("/stats/tags/likes", tsh.HandleLikes)
+		r.GET("/stats/tags/selections", tsh.HandleSelections)
 		r.GET("/selection", selectionHander.Handle)
 		r.GET("/image/:size/:id", ih.HandleImage)
 		r.GET("/image/unity/:mask/:version/:size", ih.HandleUnity)
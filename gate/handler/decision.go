@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/artchitector/artchitect/model"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type DecisionResponse struct {
+	model.Decision
+	Value string // what was chosen by this decision (version, seed, tag), if known
+	Used  bool   // false for tags, which were re-drawn because of conflict with dictionary rules
+}
+
+type DecisionHandler struct {
+	decisionRepository decisionRepository
+	artsRepository     artsRepository
+}
+
+func NewDecisionHandler(decisionRepository decisionRepository, artsRepository artsRepository) *DecisionHandler {
+	return &DecisionHandler{decisionRepository, artsRepository}
+}
+
+// Handle returns all entropy decisions (with raw values and frame time) that made the card
+func (dh *DecisionHandler) Handle(c *gin.Context) {
+	var request CardRequest
+	if err := c.ShouldBindUri(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	art, err := dh.artsRepository.GetArt(c, request.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	decisions, err := dh.decisionRepository.GetArtDecisions(c, art.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]DecisionResponse, 0, len(decisions))
+	for _, decision := range decisions {
+		item := DecisionResponse{Decision: decision, Value: decision.Label, Used: !decision.Rejected}
+		if decision.Purpose == model.DecisionPurposeSeed {
+			item.Value = fmt.Sprintf("%d", decision.Result)
+		}
+		response = append(response, item)
+	}
+	c.JSON(http.StatusOK, response)
+}
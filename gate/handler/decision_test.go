@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testArtsRepository struct {
+	arts map[uint]model.Art
+}
+
+func (r *testArtsRepository) GetArt(ctx context.Context, ID uint) (model.Art, error) {
+	art, found := r.arts[ID]
+	if !found {
+		return model.Art{}, errors.Wrapf(gorm.ErrRecordNotFound, "[test] failed to find art %d", ID)
+	}
+	return art, nil
+}
+
+func (r *testArtsRepository) GetLastArts(ctx context.Context, count uint) ([]model.Art, error) {
+	return nil, nil
+}
+
+func (r *testArtsRepository) GetArts(ctx context.Context, IDs []uint) ([]model.Art, error) {
+	return nil, nil
+}
+
+func (r *testArtsRepository) GetArtsByRange(start uint, end uint) ([]model.Art, error) {
+	return nil, nil
+}
+
+func (r *testArtsRepository) Like(ctx context.Context, cardID uint) error {
+	return nil
+}
+
+func (r *testArtsRepository) Unlike(ctx context.Context, cardID uint) error {
+	return nil
+}
+
+type testDecisionRepository struct {
+	decisions []model.Decision
+}
+
+func (r *testDecisionRepository) GetArtDecisions(ctx context.Context, artID uint) ([]model.Decision, error) {
+	result := make([]model.Decision, 0)
+	for _, decision := range r.decisions {
+		if decision.ArtID == artID {
+			result = append(result, decision)
+		}
+	}
+	return result, nil
+}
+
+func serve(method string, path string, handle gin.HandlerFunc, url string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Handle(method, path, handle)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+	return w
+}
+
+func TestDecisionHandler(t *testing.T) {
+	arts := &testArtsRepository{arts: map[uint]model.Art{
+		7: {ID: 7, Spell: model.Spell{Tags: "sun,moon", Seed: 12345}},
+	}}
+	decisions := &testDecisionRepository{decisions: []model.Decision{
+		{ID: 1, ArtID: 7, Purpose: model.DecisionPurposeVersion, Total: 2, Result: 1, Label: "v2"},
+		{ID: 2, ArtID: 7, Purpose: model.DecisionPurposeSeed, Total: 4294967295, Result: 12345, Raw: "98765"},
+		{ID: 3, ArtID: 7, Purpose: model.DecisionPurposeTag, Total: 3, Result: 0, Label: "sun"},
+		{ID: 4, ArtID: 7, Purpose: model.DecisionPurposeTag, Total: 3, Result: 0, Label: "sun", Rejected: true}, // duplicate, re-drawn
+		{ID: 5, ArtID: 7, Purpose: model.DecisionPurposeTag, Total: 3, Result: 1, Label: "moon"},
+		{ID: 6, ArtID: 8, Purpose: model.DecisionPurposeTag, Total: 3, Result: 1, Label: "moon"},
+	}}
+	handle := NewDecisionHandler(decisions, arts).Handle
+
+	w := serve(http.MethodGet, "/card/:id/decisions", handle, "/card/7/decisions")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response []DecisionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response, 5)
+
+	values := make([]string, 0, len(response))
+	used := make([]bool, 0, len(response))
+	for _, item := range response {
+		assert.Equal(t, uint(7), item.ArtID)
+		values = append(values, item.Value)
+		used = append(used, item.Used)
+	}
+	assert.Equal(t, []string{"v2", "12345", "sun", "sun", "moon"}, values)
+	assert.Equal(t, []bool{true, true, true, false, true}, used)
+	assert.Equal(t, "98765", response[1].Raw)
+
+	w = serve(http.MethodGet, "/card/:id/decisions", handle, "/card/9/decisions")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(http.MethodGet, "/card/:id/decisions", handle, "/card/abc/decisions")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -21,7 +21,7 @@ type lotteryRepository interface {
 }
 
 type prayRepository interface {
-	MakePray(ctx context.Context, password string) (model.Pray, error)
+	MakePray(ctx context.Context, password string, cardID uint, crossCardID uint, remix string) (model.Pray, error)
 	GetPrayWithPassword(ctx context.Context, prayId uint, password string) (model.Pray, error)
 	GetQueueBeforePray(ctx context.Context, prayID uint) (uint, error)
 }
@@ -58,3 +58,19 @@ type unityRepository interface {
 type enhotter interface {
 	ReloadCardWithoutImage(ctx context.Context, cardID uint)
 }
+
+type searchRepository interface {
+	SearchArts(ctx context.Context, search model.ArtSearch) ([]model.Art, error)
+}
+
+type tagStatsRepository interface {
+	GetTagFrequency(ctx context.Context, version string, limit uint) ([]model.TagStat, error)
+	GetTagFrequencyByVersion(ctx context.Context, limit uint) ([]model.TagVersionStat, error)
+	GetTagPairs(ctx context.Context, version string, tag string, limit uint) ([]model.TagPair, error)
+	GetTagLikes(ctx context.Context, version string, minArts uint, limit uint) ([]model.TagLikes, error)
+	GetTagSelections(ctx context.Context, version string, limit uint) ([]model.TagSelectionStat, error)
+}
+
+type decisionRepository interface {
+	GetArtDecisions(ctx context.Context, artID uint) ([]model.Decision, error)
+}
@@ -57,7 +57,7 @@ func (lh *LikeHandler) Handle(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	log.Info().Msgf("userID is %d, artchitector is %d")
+	log.Info().Msgf("userID is %d, artchitector is %d", userID, lh.artchitector)
 	go func(liked bool) {
 		if liked {
 			if err := lh.cardsRepository.Like(c, r.CardID); err != nil {
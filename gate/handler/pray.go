@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/artchitector/artchitect/model"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -8,8 +9,12 @@ import (
 	"net/http"
 )
 
+// PrayCreateRequest - pray for new art, or for remix of card CardID (kind Remix, CrossCardID is second card for crossover)
 type PrayCreateRequest struct {
-	Password string `json:"password" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	CardID      uint   `json:"card_id"`
+	CrossCardID uint   `json:"cross_card_id"`
+	Remix       string `json:"remix"`
 }
 
 type PrayAnswerRequest struct {
@@ -25,10 +30,11 @@ type PrayAnswer struct {
 
 type PrayHandler struct {
 	prayRepository prayRepository
+	artsRepository artsRepository
 }
 
-func NewPrayHandler(prayRepository prayRepository) *PrayHandler {
-	return &PrayHandler{prayRepository}
+func NewPrayHandler(prayRepository prayRepository, artsRepository artsRepository) *PrayHandler {
+	return &PrayHandler{prayRepository, artsRepository}
 }
 
 func (ph *PrayHandler) Handle(c *gin.Context) {
@@ -37,8 +43,12 @@ func (ph *PrayHandler) Handle(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
 		return
 	}
-	log.Info().Msgf("[pray] incoming pray")
-	pray, err := ph.prayRepository.MakePray(c, r.Password)
+	if status, err := ph.validateRemix(c, &r); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	log.Info().Msgf("[pray] incoming pray (card=%d, remix=%s)", r.CardID, r.Remix)
+	pray, err := ph.prayRepository.MakePray(c, r.Password, r.CardID, r.CrossCardID, r.Remix)
 	if err != nil {
 		log.Err(err).Send()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "answers not available at the moment. sorry, maybe later."})
@@ -48,6 +58,44 @@ func (ph *PrayHandler) Handle(c *gin.Context) {
 	c.JSON(http.StatusOK, pray.ID)
 }
 
+// validateRemix checks remix kind and target cards. Empty kind is crossover, if second card is given, otherwise new seed
+func (ph *PrayHandler) validateRemix(c *gin.Context, r *PrayCreateRequest) (int, error) {
+	if r.CardID == 0 {
+		if r.Remix != "" || r.CrossCardID != 0 {
+			return http.StatusBadRequest, errors.New("remix needs card_id")
+		}
+		return http.StatusOK, nil
+	}
+	if r.Remix == "" {
+		r.Remix = model.RemixNewSeed
+		if r.CrossCardID != 0 {
+			r.Remix = model.RemixCrossover
+		}
+	}
+	known := false
+	for _, kind := range model.RemixKinds {
+		known = known || kind == r.Remix
+	}
+	if !known {
+		return http.StatusBadRequest, errors.Errorf("unknown remix %s", r.Remix)
+	}
+	if (r.Remix == model.RemixCrossover) != (r.CrossCardID != 0) {
+		return http.StatusBadRequest, errors.New("cross_card_id is needed only for crossover")
+	}
+	for _, cardID := range []uint{r.CardID, r.CrossCardID} {
+		if cardID == 0 {
+			continue
+		}
+		if _, err := ph.artsRepository.GetArt(c, cardID); errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, errors.Errorf("card %d not found", cardID)
+		} else if err != nil {
+			log.Err(err).Send()
+			return http.StatusInternalServerError, errors.New("answers not available at the moment. sorry, maybe later.")
+		}
+	}
+	return http.StatusOK, nil
+}
+
 func (ph *PrayHandler) HandleAnswer(c *gin.Context) {
 	var request PrayAnswerRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
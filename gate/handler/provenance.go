@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+const ProvenanceAlgorithm = "HMAC-SHA256"
+
+// Provenance - everything what made the card. Signature is calculated over JSON of this struct, so fields order is a contract
+type Provenance struct {
+	CardID    uint      `json:"card_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ParentID  uint      `json:"parent_id"`
+	Remix     string    `json:"remix"`
+
+	Version        string    `json:"version"`
+	DictionaryHash string    `json:"dictionary_hash"`
+	Tags           string    `json:"tags"`
+	Seed           uint      `json:"seed"`
+	SeedRaw        string    `json:"seed_raw"`
+	SeedFrameTime  time.Time `json:"seed_frame_time"`
+
+	EngineModel    string  `json:"engine_model"`
+	NegativePrompt string  `json:"negative_prompt"`
+	Sampler        string  `json:"sampler"`
+	Steps          uint    `json:"steps"`
+	CFGScale       float64 `json:"cfg_scale"`
+	Width          uint    `json:"width"`
+	Height         uint    `json:"height"`
+	Upscale        uint    `json:"upscale"`
+
+	ArtistVersion    string `json:"artist_version"`
+	ModelChecksum    string `json:"model_checksum"`
+	WatermarkVersion string `json:"watermark_version"`
+	ImageHash        string `json:"image_hash"` // sha256 of full-size image, binds signature to the picture itself
+}
+
+// SignedProvenance - manifest of card. Anyone can send it to /provenance/verify to check that card came from Artchitect
+type SignedProvenance struct {
+	Provenance Provenance `json:"provenance"`
+	Algorithm  string     `json:"algorithm"`
+	Signature  string     `json:"signature"` // hex, empty if gate has no secret
+}
+
+type ProvenanceHandler struct {
+	artsRepository artsRepository
+	secret         []byte
+}
+
+func NewProvenanceHandler(artsRepository artsRepository, secret string) *ProvenanceHandler {
+	return &ProvenanceHandler{artsRepository, []byte(secret)}
+}
+
+func NewProvenance(art model.Art) Provenance {
+	return Provenance{
+		CardID:    art.ID,
+		CreatedAt: art.CreatedAt.UTC(),
+		ParentID:  art.ParentID,
+		Remix:     art.Remix,
+
+		Version:        art.Spell.Version,
+		DictionaryHash: art.Spell.DictionaryHash,
+		Tags:           art.Spell.Tags,
+		Seed:           art.Spell.Seed,
+		SeedRaw:        art.Spell.SeedRaw,
+		SeedFrameTime:  art.Spell.SeedFrameTime.UTC(),
+
+		EngineModel:    art.Spell.EngineModel,
+		NegativePrompt: art.Spell.NegativePrompt,
+		Sampler:        art.Spell.Sampler,
+		Steps:          art.Spell.Steps,
+		CFGScale:       art.Spell.CFGScale,
+		Width:          art.Spell.Width,
+		Height:         art.Spell.Height,
+		Upscale:        art.Spell.Upscale,
+
+		ArtistVersion:    art.ArtistVersion,
+		ModelChecksum:    art.ModelChecksum,
+		WatermarkVersion: art.WatermarkVersion,
+		ImageHash:        art.ImageHash,
+	}
+}
+
+// Handle returns signed provenance of the card
+func (ph *ProvenanceHandler) Handle(c *gin.Context) {
+	var request CardRequest
+	if err := c.ShouldBindUri(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	art, err := ph.artsRepository.GetArt(c, request.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := SignedProvenance{Provenance: NewProvenance(art), Algorithm: ProvenanceAlgorithm}
+	if len(ph.secret) > 0 {
+		if response.Signature, err = ph.sign(response.Provenance); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// HandleVerify checks signature of manifest and that manifest is equal to current provenance of the card
+func (ph *ProvenanceHandler) HandleVerify(c *gin.Context) {
+	if len(ph.secret) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "provenance signing is disabled"})
+		return
+	}
+	var request SignedProvenance
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	signature, err := ph.sign(request.Provenance)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if request.Algorithm != ProvenanceAlgorithm || !hmac.Equal([]byte(signature), []byte(request.Signature)) {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "wrong signature"})
+		return
+	}
+
+	art, err := ph.artsRepository.GetArt(c, request.Provenance.CardID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "card not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if current, err := ph.sign(NewProvenance(art)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if current != signature {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "reason": "card provenance changed"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"valid": true})
+}
+
+func (ph *ProvenanceHandler) sign(provenance Provenance) (string, error) {
+	data, err := json.Marshal(provenance)
+	if err != nil {
+		return "", errors.Wrap(err, "[provenance] failed to marshal provenance")
+	}
+	mac := hmac.New(sha256.New, ph.secret)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func post(path string, handle gin.HandlerFunc, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST(path, handle)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	return w
+}
+
+type verifyResponse struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason"`
+}
+
+func verify(t *testing.T, handler *ProvenanceHandler, manifest SignedProvenance) verifyResponse {
+	w := post("/provenance/verify", handler.HandleVerify, manifest)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response verifyResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestProvenanceHandler(t *testing.T) {
+	arts := &testArtsRepository{arts: map[uint]model.Art{
+		7: {
+			ID:        7,
+			CreatedAt: time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC),
+			Spell:     model.Spell{Tags: "sun,moon", Seed: 12345, Version: "v3"},
+			ImageHash: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		},
+	}}
+	handler := NewProvenanceHandler(arts, "secret")
+
+	w := serve(http.MethodGet, "/card/:id/provenance", handler.Handle, "/card/7/provenance")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var manifest SignedProvenance
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &manifest))
+	assert.Equal(t, ProvenanceAlgorithm, manifest.Algorithm)
+	assert.Len(t, manifest.Signature, 64)
+	assert.Equal(t, arts.arts[7].ImageHash, manifest.Provenance.ImageHash)
+
+	assert.Equal(t, verifyResponse{Valid: true}, verify(t, handler, manifest))
+
+	// manifest of other picture with the same spell
+	tampered := manifest
+	tampered.Provenance.ImageHash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	assert.Equal(t, verifyResponse{Reason: "wrong signature"}, verify(t, handler, tampered))
+
+	// signed by other gate
+	assert.Equal(t, verifyResponse{Reason: "wrong signature"}, verify(t, NewProvenanceHandler(arts, "other"), manifest))
+
+	// card changed after manifest was issued
+	card := arts.arts[7]
+	card.Spell.Tags = "sun"
+	arts.arts[7] = card
+	assert.Equal(t, verifyResponse{Reason: "card provenance changed"}, verify(t, handler, manifest))
+	delete(arts.arts, 7)
+	assert.Equal(t, verifyResponse{Reason: "card not found"}, verify(t, handler, manifest))
+
+	w = serve(http.MethodGet, "/card/:id/provenance", handler.Handle, "/card/7/provenance")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// gate without secret does not sign and does not verify
+	unsigned := NewProvenanceHandler(&testArtsRepository{arts: map[uint]model.Art{7: {ID: 7}}}, "")
+	w = serve(http.MethodGet, "/card/:id/provenance", unsigned.Handle, "/card/7/provenance")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &manifest))
+	assert.Empty(t, manifest.Signature)
+	w = post("/provenance/verify", unsigned.HandleVerify, manifest)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"github.com/artchitector/artchitect/model"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	SearchDefaultLimit = 50
+	SearchMaxLimit     = 200
+)
+
+/*
+SearchRequest - query of /search. Tags lists are comma-separated.
+
+	/search?q=cathedral&tags=Angel,wings&none=black and white&version=v1.2&from=2023-01-01&min_likes=1&selected=true&cursor=15000
+*/
+type SearchRequest struct {
+	Query    string `form:"q"`
+	Tags     string `form:"tags"` // all of tags
+	Any      string `form:"any"`  // any of tags
+	None     string `form:"none"` // none of tags
+	Seed     *uint  `form:"seed"`
+	Version  string `form:"version"`
+	From     string `form:"from"` // RFC3339 or 2006-01-02
+	To       string `form:"to"`
+	MinLikes uint   `form:"min_likes"`
+	Selected bool   `form:"selected"`
+	Cursor   uint   `form:"cursor"` // NextCursor of previous page
+	Limit    uint   `form:"limit"`
+}
+
+type SearchResponse struct {
+	Cards      []model.Art
+	NextCursor uint // 0 - no more cards
+}
+
+type SearchHandler struct {
+	searchRepository searchRepository
+}
+
+func NewSearchHandler(searchRepository searchRepository) *SearchHandler {
+	return &SearchHandler{searchRepository}
+}
+
+func (sh *SearchHandler) Handle(c *gin.Context) {
+	var request SearchRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search, err := request.toSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cards, err := sh.searchRepository.SearchArts(c, search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	response := SearchResponse{Cards: cards}
+	if uint(len(cards)) == search.Limit {
+		response.NextCursor = cards[len(cards)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (r SearchRequest) toSearch() (model.ArtSearch, error) {
+	search := model.ArtSearch{
+		Query:    strings.TrimSpace(r.Query),
+		TagsAll:  splitSearchTags(r.Tags),
+		TagsAny:  splitSearchTags(r.Any),
+		TagsNone: splitSearchTags(r.None),
+		Seed:     r.Seed,
+		Version:  r.Version,
+		MinLikes: r.MinLikes,
+		Selected: r.Selected,
+		BeforeID: r.Cursor,
+		Limit:    r.Limit,
+	}
+	if search.Limit == 0 {
+		search.Limit = SearchDefaultLimit
+	} else if search.Limit > SearchMaxLimit {
+		search.Limit = SearchMaxLimit
+	}
+	var err error
+	if search.From, err = parseSearchTime(r.From); err != nil {
+		return search, errors.Wrap(err, "wrong from")
+	}
+	if search.To, err = parseSearchTime(r.To); err != nil {
+		return search, errors.Wrap(err, "wrong to")
+	}
+	return search, nil
+}
+
+func splitSearchTags(tags string) []string {
+	result := model.ParseTags(tags)
+	for idx := range result {
+		result[idx] = strings.ToLower(result[idx])
+	}
+	return result
+}
+
+func parseSearchTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, err
+		}
+	}
+	return &t, nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+const (
+	TagStatsDefaultLimit   = 100
+	TagStatsMaxLimit       = 1000
+	TagStatsDefaultMinArts = 10
+)
+
+// TagStatsRequest - query of /stats/tags/* (version "" - all versions, tag "" - all pairs)
+type TagStatsRequest struct {
+	Version string `form:"version"`
+	Tag     string `form:"tag"`
+	Limit   uint   `form:"limit"`
+	MinArts uint   `form:"min_arts"`
+}
+
+// TagStatsHandler - which tags are frequent, which are together and which produce most liked and selected arts
+type TagStatsHandler struct {
+	tagStatsRepository tagStatsRepository
+}
+
+func NewTagStatsHandler(tagStatsRepository tagStatsRepository) *TagStatsHandler {
+	return &TagStatsHandler{tagStatsRepository}
+}
+
+func (h *TagStatsHandler) HandleFrequency(c *gin.Context) {
+	request, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	stats, err := h.tagStatsRepository.GetTagFrequency(c, request.Version, request.Limit)
+	h.respond(c, stats, err)
+}
+
+func (h *TagStatsHandler) HandleVersions(c *gin.Context) {
+	request, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	stats, err := h.tagStatsRepository.GetTagFrequencyByVersion(c, request.Limit)
+	h.respond(c, stats, err)
+}
+
+func (h *TagStatsHandler) HandlePairs(c *gin.Context) {
+	request, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	pairs, err := h.tagStatsRepository.GetTagPairs(c, request.Version, request.Tag, request.Limit)
+	h.respond(c, pairs, err)
+}
+
+func (h *TagStatsHandler) HandleLikes(c *gin.Context) {
+	request, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	stats, err := h.tagStatsRepository.GetTagLikes(c, request.Version, request.MinArts, request.Limit)
+	h.respond(c, stats, err)
+}
+
+func (h *TagStatsHandler) HandleSelections(c *gin.Context) {
+	request, ok := h.bind(c)
+	if !ok {
+		return
+	}
+	stats, err := h.tagStatsRepository.GetTagSelections(c, request.Version, request.Limit)
+	h.respond(c, stats, err)
+}
+
+func (h *TagStatsHandler) bind(c *gin.Context) (TagStatsRequest, bool) {
+	var request TagStatsRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return request, false
+	}
+	if request.Limit == 0 {
+		request.Limit = TagStatsDefaultLimit
+	} else if request.Limit > TagStatsMaxLimit {
+		request.Limit = TagStatsMaxLimit
+	}
+	if request.MinArts == 0 {
+		request.MinArts = TagStatsDefaultMinArts
+	}
+	return request, true
+}
+
+func (h *TagStatsHandler) respond(c *gin.Context, stats interface{}, err error) {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
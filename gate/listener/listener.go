@@ -59,6 +59,7 @@ func (l *Listener) Run(ctx context.Context) error {
 		model.ChannelUnity,
 		model.ChannelHeart,
 		model.ChannelEntropy,
+		model.ChannelEntropyHealth,
 	)
 	for {
 		select {
@@ -85,6 +86,7 @@ func (l *Listener) handle(ctx context.Context, msg *redis.Message) error {
 	case model.ChannelLottery:
 	case model.ChannelUnity:
 	case model.ChannelHeart:
+	case model.ChannelEntropyHealth:
 
 	case model.ChannelPrehotCard:
 		if err := l.handlePrehotCard(ctx, msg); err != nil {
@@ -17,6 +17,8 @@ type Env struct {
 	ArtchitectHost string
 	AllowFakeAuth  bool
 
+	ProvenanceSecret string // HMAC key of card provenance manifests (empty - manifests are not signed)
+
 	// telegram constants
 	Telegram10BotToken   string // 10bot (is for maintenance and secure use to control artchitect.space). Secured with single account usage.
 	TelegramABotToken    string // ABot (is for everyone: login, prayer etc)
@@ -45,6 +47,8 @@ func initEnv() *Env {
 		ArtchitectHost: os.Getenv("ARTCHITECT_HOST"),
 		AllowFakeAuth:  os.Getenv("ALLOW_FAKE_AUTH") == "true",
 
+		ProvenanceSecret: os.Getenv("PROVENANCE_SECRET"),
+
 		Telegram10BotToken:   os.Getenv("TELEGRAM_10BOT_TOKEN"),
 		TelegramABotToken:    os.Getenv("TELEGRAM_ABOT_TOKEN"),
 		ChatID10:             os.Getenv("CHAT_ID_10MIN"),
@@ -4,17 +4,47 @@ import (
 	"time"
 )
 
+/*
+States of art creation. Art row is saved before images are uploaded, so creation is two-phase:
+painted (row saved, images not uploaded) -> stored (full-size image is in storage) -> published (all sizes are in memory).
+Only published arts are shown. Arts stuck in painted/stored states are finished or removed by reconciler.
+*/
+const (
+	ArtStatePainted   = "painted"
+	ArtStateStored    = "stored"
+	ArtStatePublished = "published"
+)
+
 // TODO split card table and raw image data into separate tables and migrate database
 type Art struct {
-	ID                uint `gorm:"primarykey"`
-	CreatedAt         time.Time
+	ID                uint      `gorm:"primarykey"`
+	CreatedAt         time.Time `gorm:"index"`
 	UpdatedAt         time.Time
-	SpellID           uint
+	SpellID           uint `gorm:"index"`
 	Spell             Spell
 	Version           string // in what environment made card (tags set, version on StableDiffusion etc.)
+	ParentID          uint   `gorm:"not null;default:0;index"` // art, which spell was remixed into this art (0 - original art)
+	Remix             string // kind of remix (model.Remix*), empty for original art
+	ArtistVersion     string // version of artist service, which painted art (empty - unknown)
+	ModelChecksum     string // checksum of engine model (empty - unknown)
+	WatermarkVersion  string
+	ImageHash         string // sha256 (hex) of full-size jpeg, as it is saved in storage (empty - unknown)
 	PaintTime         uint   // seconds, how much paint took
-	UploadedToStorage bool   `gorm:"not null;default:false"` // full-size file uploaded to s3-storage
-	UploadedToMemory  bool   `gorm:"not null;default:false"` // file was uploaded to storage in all sizes as files
-	Likes             uint   `gorm:"not null;default:0"`     // total number of likes
-	Liked             bool   `gorm:"-"`                      // runtime flag, means that current user liked this image
+	State             string `gorm:"not null;default:published;index"` // model.ArtState*, arts made before states are published
+	UploadedToStorage bool   `gorm:"not null;default:false"`           // full-size file uploaded to s3-storage
+	UploadedToMemory  bool   `gorm:"not null;default:false"`           // file was uploaded to storage in all sizes as files
+	Likes             uint   `gorm:"not null;default:0"`               // total number of likes
+	Liked             bool   `gorm:"-"`                                // runtime flag, means that current user liked this image
+}
+
+// ArtStateCount - number of arts in state (report of creation consistency)
+type ArtStateCount struct {
+	State string
+	Total uint
+}
+
+// EngineInfo - what engine tells about itself with painted image (for provenance of art)
+type EngineInfo struct {
+	ArtistVersion string
+	ModelChecksum string
 }
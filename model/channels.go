@@ -1,14 +1,15 @@
 package model
 
 const (
-	ChannelTick         = "tick"
-	ChannelPrehotCard   = "prehot_card" // update card cache before it will appear in common list (1 second before to resize images)
-	ChannelNewCard      = "new_card"
-	ChannelCreation     = "creation"
-	ChannelNewSelection = "new_selection"
-	ChannelLottery      = "lottery"
-	ChannelUnity        = "unity"
-	ChannelHeart        = "heart"
-	ChannelEntropy      = "entropy"
-	ChannelEntropyMini  = "entropy_mini"
+	ChannelTick          = "tick"
+	ChannelPrehotCard    = "prehot_card" // update card cache before it will appear in common list (1 second before to resize images)
+	ChannelNewCard       = "new_card"
+	ChannelCreation      = "creation"
+	ChannelNewSelection  = "new_selection"
+	ChannelLottery       = "lottery"
+	ChannelUnity         = "unity"
+	ChannelHeart         = "heart"
+	ChannelEntropy       = "entropy"
+	ChannelEntropyMini   = "entropy_mini"
+	ChannelEntropyHealth = "entropy_health"
 )
@@ -12,8 +12,7 @@ const (
 	Version20 = "v2.0" // not supported
 )
 
-// v1 and v1.1 is old. disabled
-var AvailableVersions = []string{ /*Version1, Version11, */ Version12}
+// available versions are in soul manifest directory (soul/files/versions), v1 and v1.1 is old. disabled
 
 const (
 	MaxSeed = uint(4294967295)
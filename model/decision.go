@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	DecisionPurposeUnknown             = "unknown"
+	DecisionPurposeVersion             = "version"
+	DecisionPurposeSeed                = "seed"
+	DecisionPurposeTagsCount           = "tags_count"
+	DecisionPurposeTag                 = "tag"
+	DecisionPurposeSampler             = "sampler"
+	DecisionPurposeSteps               = "steps"
+	DecisionPurposeCFGScale            = "cfg_scale"
+	DecisionPurposeRemixSwap           = "remix_swap"      // which tag of parent spell is swapped
+	DecisionPurposeRemixCrossover      = "remix_crossover" // cut points of parents tags
+	DecisionPurposeLotteryTotalWinners = "lottery_total_winners"
+	DecisionPurposeLotteryWinner       = "lottery_winner"
+	DecisionPurposeUnityLead           = "unity_lead"
+	DecisionPurposeHeartDream          = "heart_dream"
+	DecisionPurposeGift                = "gift"
+)
+
+// Decision - one Entropy.Select call: what was selected, from how many variants and by which light fluctuation.
+// Decisions made for the art (version, seed, tags) are linked with ArtID.
+type Decision struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Purpose   string
+	ArtID     uint   `gorm:"index"` // 0 if decision not related to a single art
+	Total     uint   // totalElements in Select, total weight in SelectWeighted
+	Point     uint   // selected point in [0, Total) (equal to Result for Select, falls into weight range of Result for SelectWeighted)
+	Result    uint   // selected index of variant
+	Label     string // what was selected (tag, version), if caller gave names of variants
+	Raw       string
+	Attempts  uint      // how many raw values was taken (rejection sampling drops values from the tail)
+	Rejected  bool      // selected variant was not used (tag re-drawn because of dictionary rules)
+	FrameTime time.Time // time of frame, which produced raw value
+}
@@ -8,9 +8,20 @@ const (
 	PrayStateAnswered = "answered"
 )
 
+const (
+	RemixNewSeed   = "seed"      // same tags, new seed from entropy
+	RemixSwapTag   = "swap_tag"  // same seed, one tag swapped by entropy
+	RemixCrossover = "crossover" // tags of two arts crossed over, new seed
+)
+
+var RemixKinds = []string{RemixNewSeed, RemixSwapTag, RemixCrossover}
+
 type Pray struct {
 	gorm.Model
-	Password string
-	State    string
-	Answer   uint
+	Password    string
+	State       string
+	Answer      uint
+	CardID      uint   `gorm:"not null;default:0"` // art to remix (0 - answer is new art)
+	CrossCardID uint   `gorm:"not null;default:0"` // second art for RemixCrossover
+	Remix       string // kind of remix (model.Remix*)
 }
@@ -26,6 +26,7 @@ func (pr *ArtRepository) GetLastArts(ctx context.Context, count uint) ([]model.A
 	arts := make([]model.Art, 0, count)
 	err := pr.db.
 		Joins("Spell").
+		Where("arts.state = ?", model.ArtStatePublished).
 		Limit(int(count)).
 		Order("arts.id desc").
 		Limit(int(count)).
@@ -43,6 +44,7 @@ func (pr *ArtRepository) GetArt(ctx context.Context, ID uint) (model.Art, error)
 	err := pr.db.
 		Joins("Spell").
 		Where("arts.id = ?", ID).
+		Where("arts.state = ?", model.ArtStatePublished).
 		Last(&art).
 		Error
 	if err != nil {
@@ -57,6 +59,7 @@ func (pr *ArtRepository) GetArtsByRange(start uint, end uint) ([]model.Art, erro
 	log.Info().Msgf("[art_repo] get arts between %d and %d", start, end)
 	err := pr.db.Joins("Spell").
 		Where("arts.id between ? and ?", start, end).
+		Where("arts.state = ?", model.ArtStatePublished).
 		Order("arts.id asc").
 		Find(&arts).
 		Error
@@ -65,7 +68,7 @@ func (pr *ArtRepository) GetArtsByRange(start uint, end uint) ([]model.Art, erro
 
 func (pr *ArtRepository) GetArts(ctx context.Context, IDs []uint) ([]model.Art, error) {
 	var arts []model.Art
-	err := pr.db.Joins("Spell").Where("arts.id in (?)", IDs).Find(&arts).Error
+	err := pr.db.Joins("Spell").Where("arts.id in (?) and arts.state = ?", IDs, model.ArtStatePublished).Find(&arts).Error
 	return arts, err
 }
 
@@ -81,13 +84,17 @@ func (pr *ArtRepository) DeleteArt(ctx context.Context, artID uint) error {
 
 func (pr *ArtRepository) GetArtsIDsByPeriod(ctx context.Context, start time.Time, end time.Time) ([]uint, error) {
 	var ids []uint
-	err := pr.db.Model(&model.Art{}).Select("id").Where("created_at between ? and ?", start, end).Find(&ids).Error
+	err := pr.db.Model(&model.Art{}).
+		Select("id").
+		Where("created_at between ? and ?", start, end).
+		Where("state = ?", model.ArtStatePublished).
+		Find(&ids).Error
 	return ids, err
 }
 
 func (pr *ArtRepository) GetTotalArts(ctx context.Context) (uint, error) {
 	var count uint
-	err := pr.db.Select("count(id)").Model(&model.Art{}).Find(&count).Error
+	err := pr.db.Select("count(id)").Model(&model.Art{}).Where("state = ?", model.ArtStatePublished).Find(&count).Error
 	return count, err
 }
 
@@ -112,7 +119,11 @@ func (pr *ArtRepository) GetOriginSelectedArt(ctx context.Context) (model.Art, e
 
 func (pr *ArtRepository) GetOriginSelectedArtByPeriod(ctx context.Context, start time.Time, end time.Time) (model.Art, error) {
 	var total uint
-	err := pr.db.Select("count(id)").Where("created_at between ? and ?", start, end).Model(&model.Art{}).Scan(&total).Error
+	err := pr.db.Select("count(id)").
+		Where("created_at between ? and ?", start, end).
+		Where("state = ?", model.ArtStatePublished).
+		Model(&model.Art{}).
+		Scan(&total).Error
 	if err != nil {
 		return model.Art{}, errors.Wrapf(err, "[art_repository] failed to get number of arts")
 	}
@@ -121,7 +132,12 @@ func (pr *ArtRepository) GetOriginSelectedArtByPeriod(ctx context.Context, start
 		return model.Art{}, errors.Wrapf(err, "[art_repository] failed to get selection from origin")
 	}
 	var art model.Art
-	err = pr.db.Where("created_at between ? and ?", start, end).Limit(1).Offset(int(selection)).First(&art).Error
+	err = pr.db.
+		Where("created_at between ? and ?", start, end).
+		Where("state = ?", model.ArtStatePublished).
+		Limit(1).
+		Offset(int(selection)).
+		First(&art).Error
 	if err != nil {
 		return model.Art{}, errors.Wrapf(err, "[art_repository] failed to get art with offset %d", selection)
 	}
@@ -132,7 +148,11 @@ func (pr *ArtRepository) GetAnyCardIDFromHundred(ctx context.Context, rank uint,
 	end := start + rank - 1
 	log.Info().Msgf("[art_repo] GetAnyCardIDFromHundred s:%d, e:%d", start, end)
 	var variants uint
-	err := pr.db.Select("count(id)").Where("id between ? and ?", start, end).Model(&model.Art{}).Scan(&variants).Error
+	err := pr.db.Select("count(id)").
+		Where("id between ? and ?", start, end).
+		Where("state = ?", model.ArtStatePublished).
+		Model(&model.Art{}).
+		Scan(&variants).Error
 	if err != nil {
 		return 0, errors.Wrapf(err, "[art_repo] failed to get variants from r:%d h:%d", rank, start)
 	}
@@ -147,6 +167,7 @@ func (pr *ArtRepository) GetAnyCardIDFromHundred(ctx context.Context, rank uint,
 		Select("id").
 		Model(&model.Art{}).
 		Where("id between ? and ?", start, end).
+		Where("state = ?", model.ArtStatePublished).
 		Order("id asc").
 		Limit(1).
 		Offset(int(offset)).
@@ -162,6 +183,7 @@ func (pr *ArtRepository) GetArtWithOffset(offset uint) (model.Art, error) {
 	var art model.Art
 	err := pr.db.
 		Joins("Spell").
+		Where("arts.state = ?", model.ArtStatePublished).
 		Order("arts.id asc").
 		Limit(1).
 		Offset(int(offset)).
@@ -191,6 +213,7 @@ func (pr *ArtRepository) GetPreviousCardID(ctx context.Context, artID uint) (uin
 	err := pr.db.Select("id").
 		Model(&model.Art{}).
 		Where("id < ?", artID).
+		Where("state = ?", model.ArtStatePublished).
 		Order("id desc").
 		Limit(1).
 		Scan(&id).Error
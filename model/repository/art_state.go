@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// GetStuckArts returns arts in intermediate states (painted, stored), which were not changed since before
+func (pr *ArtRepository) GetStuckArts(ctx context.Context, before time.Time, limit uint) ([]model.Art, error) {
+	arts := make([]model.Art, 0, limit)
+	err := pr.db.WithContext(ctx).
+		Where("state in (?)", []string{model.ArtStatePainted, model.ArtStateStored}).
+		Where("updated_at < ?", before).
+		Order("id asc").
+		Limit(int(limit)).
+		Find(&arts).
+		Error
+	return arts, errors.Wrap(err, "[art_repository] failed to get stuck arts")
+}
+
+// SetArtState moves art to state, upload flags follow the state
+func (pr *ArtRepository) SetArtState(ctx context.Context, artID uint, state string) error {
+	err := pr.db.WithContext(ctx).
+		Model(&model.Art{}).
+		Where("id = ?", artID).
+		Updates(map[string]interface{}{
+			"state":               state,
+			"uploaded_to_storage": state != model.ArtStatePainted,
+			"uploaded_to_memory":  state == model.ArtStatePublished,
+			"updated_at":          time.Now(),
+		}).
+		Error
+	return errors.Wrapf(err, "[art_repository] failed to set state %s of art %d", state, artID)
+}
+
+func (pr *ArtRepository) CountArtsByState(ctx context.Context) ([]model.ArtStateCount, error) {
+	counts := make([]model.ArtStateCount, 0)
+	err := pr.db.WithContext(ctx).
+		Model(&model.Art{}).
+		Select("state, count(id) as total").
+		Group("state").
+		Order("state").
+		Scan(&counts).
+		Error
+	return counts, errors.Wrap(err, "[art_repository] failed to count arts by state")
+}
+
+// CountPublishedWithoutUploads - published arts, which have no upload flags (were published without uploads)
+func (pr *ArtRepository) CountPublishedWithoutUploads(ctx context.Context) (uint, error) {
+	var total uint
+	err := pr.db.WithContext(ctx).
+		Model(&model.Art{}).
+		Select("count(id)").
+		Where("state = ?", model.ArtStatePublished).
+		Where("not uploaded_to_storage or not uploaded_to_memory").
+		Scan(&total).
+		Error
+	return total, errors.Wrap(err, "[art_repository] failed to count published arts without uploads")
+}
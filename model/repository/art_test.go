@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testEntropy selects the last variant and remembers number of variants
+type testEntropy struct {
+	total uint
+}
+
+func (e *testEntropy) Select(ctx context.Context, totalVariants uint) (uint, error) {
+	e.total = totalVariants
+	return totalVariants - 1, nil
+}
+
+// TestPublishedArts - arts, which are not published yet, are not visible for lotteries, gifter and cards navigation
+func TestPublishedArts(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	createArt(t, db, model.Art{ID: 1})
+	createArt(t, db, model.Art{ID: 2, State: model.ArtStatePainted})
+	createArt(t, db, model.Art{ID: 3, State: model.ArtStatePublished})
+	createArt(t, db, model.Art{ID: 4, State: model.ArtStateStored})
+	entropy := &testEntropy{}
+	repo := NewCardRepository(db, entropy)
+	start, end := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	ids, err := repo.GetArtsIDsByPeriod(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if expected := []uint{1, 3}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("expected arts of period %v, got %v", expected, ids)
+	}
+
+	art, err := repo.GetOriginSelectedArtByPeriod(ctx, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if art.ID != 3 || entropy.total != 2 {
+		t.Fatalf("expected art 3 of 2 variants, got %d of %d", art.ID, entropy.total)
+	}
+
+	id, err := repo.GetAnyCardIDFromHundred(ctx, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 3 || entropy.total != 2 {
+		t.Fatalf("expected card 3 of 2 variants, got %d of %d", id, entropy.total)
+	}
+
+	for artID, expected := range map[uint]uint{5: 3, 3: 1, 1: 0} {
+		if id, err := repo.GetPreviousCardID(ctx, artID); err != nil || id != expected {
+			t.Errorf("expected previous card of %d is %d, got %d (err %v)", artID, expected, id, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"gorm.io/gorm"
+)
+
+type DecisionRepository struct {
+	db *gorm.DB
+}
+
+func NewDecisionRepository(db *gorm.DB) *DecisionRepository {
+	return &DecisionRepository{db}
+}
+
+func (r *DecisionRepository) SaveDecision(ctx context.Context, decision model.Decision) (model.Decision, error) {
+	err := r.db.Save(&decision).Error
+	return decision, err
+}
+
+func (r *DecisionRepository) GetArtDecisions(ctx context.Context, artID uint) ([]model.Decision, error) {
+	var decisions []model.Decision
+	err := r.db.
+		Where("art_id = ?", artID).
+		Order("id asc").
+		Find(&decisions).Error
+	return decisions, err
+}
@@ -16,12 +16,16 @@ func NewPrayRepository(db *gorm.DB) *PrayRepository {
 	return &PrayRepository{db: db}
 }
 
-func (pr *PrayRepository) MakePray(ctx context.Context, password string) (model.Pray, error) {
+// MakePray - cardID=0 means pray for new art, otherwise art cardID (with crossCardID for crossover) is remixed
+func (pr *PrayRepository) MakePray(ctx context.Context, password string, cardID uint, crossCardID uint, remix string) (model.Pray, error) {
 	passEncrypted := encrypt(password)
 	pray := model.Pray{
-		Password: passEncrypted,
-		State:    model.PrayStateWaiting,
-		Answer:   0,
+		Password:    passEncrypted,
+		State:       model.PrayStateWaiting,
+		Answer:      0,
+		CardID:      cardID,
+		CrossCardID: crossCardID,
+		Remix:       remix,
 	}
 	err := pr.db.Create(&pray).Error
 	return pray, err
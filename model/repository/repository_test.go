@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/artchitector/artchitect/model"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestDbDSN - environment variable with postgres for repository tests, tests are skipped without it.
+// For example: TEST_DB_DSN="host=localhost user=postgres password=*** dbname=postgres sslmode=disable"
+const TestDbDSN = "TEST_DB_DSN"
+
+// testDB connects to test postgres. Every test works in its own schema, which is dropped after the test
+func testDB(t *testing.T) *gorm.DB {
+	dsn := os.Getenv(TestDbDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set, repository tests need postgres", TestDbDSN)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:                                   logger.Default.LogMode(logger.Silent),
+		DisableForeignKeyConstraintWhenMigrating: true, // fixtures are created without lotteries and other parents
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1) // search_path is a setting of connection
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := db.Exec("create schema " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Exec("drop schema " + schema + " cascade").Error; err != nil {
+			t.Errorf("failed to drop schema %s: %s", schema, err)
+		}
+		sqlDB.Close()
+	})
+	if err := db.Exec("set search_path to " + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(model.TagsExtension).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&model.Art{},
+		&model.Spell{},
+		&model.Lottery{},
+		&model.Selection{},
+		&model.Tag{},
+		&model.SpellTag{},
+	); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// createArt saves spell with its tags and art of this spell
+func createArt(t *testing.T, db *gorm.DB, art model.Art) model.Art {
+	spell, err := NewSpellRepository(db).Save(context.Background(), art.Spell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	art.SpellID = spell.ID
+	if err := db.Omit("Spell").Create(&art).Error; err != nil {
+		t.Fatal(err)
+	}
+	art.Spell = spell
+	return art
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+)
+
+// artHasTags - subquery condition "spell of art has tag from list"
+const artHasTags = `exists (
+	select 1 from spell_tags st join tags t on t.id = st.tag_id
+	where st.spell_id = arts.spell_id and lower(t.name) in ?
+)`
+
+/*
+SearchArts finds arts by tags (normalized tables tags and spell_tags), seed, version, date, likes and lottery selections.
+Arts are sorted from newest, next page is requested with BeforeID = id of last art.
+*/
+func (pr *ArtRepository) SearchArts(ctx context.Context, search model.ArtSearch) ([]model.Art, error) {
+	query := pr.db.WithContext(ctx).
+		Joins("Spell").
+		Where("arts.state = ?", model.ArtStatePublished).
+		Order("arts.id desc").
+		Limit(int(search.Limit))
+
+	if search.BeforeID > 0 {
+		query = query.Where("arts.id < ?", search.BeforeID)
+	}
+	if search.Query != "" {
+		query = query.Where(`exists (
+			select 1 from spell_tags st join tags t on t.id = st.tag_id
+			where st.spell_id = arts.spell_id and t.name ilike ?
+		)`, "%"+escapeLike(search.Query)+"%")
+	}
+	for _, tag := range search.TagsAll {
+		query = query.Where(artHasTags, []string{tag})
+	}
+	if len(search.TagsAny) > 0 {
+		query = query.Where(artHasTags, search.TagsAny)
+	}
+	if len(search.TagsNone) > 0 {
+		query = query.Where("not "+artHasTags, search.TagsNone)
+	}
+	if search.Seed != nil {
+		query = query.Where(`"Spell".seed = ?`, *search.Seed)
+	}
+	if search.Version != "" {
+		query = query.Where("arts.version = ?", search.Version)
+	}
+	if search.From != nil {
+		query = query.Where("arts.created_at >= ?", *search.From)
+	}
+	if search.To != nil {
+		query = query.Where("arts.created_at < ?", *search.To)
+	}
+	if search.MinLikes > 0 {
+		query = query.Where("arts.likes >= ?", search.MinLikes)
+	}
+	if search.Selected {
+		query = query.Where("exists (select 1 from selections s where s.card_id = arts.id and s.deleted_at is null)")
+	}
+
+	arts := make([]model.Art, 0, search.Limit)
+	err := query.Find(&arts).Error
+	return arts, errors.Wrap(err, "[art_repository] failed to search arts")
+}
+
+func escapeLike(s string) string {
+	r := make([]rune, 0, len(s))
+	for _, c := range s {
+		if c == '%' || c == '_' || c == '\\' {
+			r = append(r, '\\')
+		}
+		r = append(r, c)
+	}
+	return string(r)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchArts(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	createArt(t, db, model.Art{ID: 1, Likes: 1, Version: "v1", Spell: model.Spell{Tags: "cathedral,sun", Seed: 100}})
+	createArt(t, db, model.Art{ID: 2, Likes: 5, Version: "v1", Spell: model.Spell{Tags: "Cathedral,moon", Seed: 200}})
+	createArt(t, db, model.Art{ID: 3, Version: "v2", Spell: model.Spell{Tags: "100%_done,moon", Seed: 300}})
+	createArt(t, db, model.Art{ID: 4, Version: "v2", State: model.ArtStatePainted, Spell: model.Spell{Tags: "cathedral,star"}})
+	createArt(t, db, model.Art{ID: 5, Likes: 3, Version: "v2", Spell: model.Spell{Tags: "sun,star", Seed: 500}})
+	if err := db.Omit("Card", "Lottery").Create(&model.Selection{CardID: 5, LotteryID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	repo := NewCardRepository(db, nil)
+	for _, index := range []string{"idx_tags_name_lower", "idx_tags_name_trgm"} {
+		if !db.Migrator().HasIndex(&model.Tag{}, index) {
+			t.Fatalf("tags have no search index %s", index)
+		}
+	}
+
+	seed := uint(100)
+	future := time.Now().Add(time.Hour)
+	cases := map[string]struct {
+		search   model.ArtSearch
+		expected []uint
+	}{
+		"all":              {model.ArtSearch{}, []uint{5, 3, 2, 1}}, // painted art is not found
+		"tags all":         {model.ArtSearch{TagsAll: []string{"cathedral"}}, []uint{2, 1}},
+		"tags all, case":   {model.ArtSearch{TagsAll: []string{"cathedral", "moon"}}, []uint{2}},
+		"tags any":         {model.ArtSearch{TagsAny: []string{"moon", "star"}}, []uint{5, 3, 2}},
+		"tags none":        {model.ArtSearch{TagsNone: []string{"cathedral", "moon"}}, []uint{5}},
+		"tags mixed":       {model.ArtSearch{TagsAll: []string{"sun"}, TagsNone: []string{"star"}}, []uint{1}},
+		"query":            {model.ArtSearch{Query: "ATHED"}, []uint{2, 1}},
+		"query percent":    {model.ArtSearch{Query: "%"}, []uint{3}},
+		"query underscore": {model.ArtSearch{Query: "_"}, []uint{3}},
+		"seed":             {model.ArtSearch{Seed: &seed}, []uint{1}},
+		"version":          {model.ArtSearch{Version: "v2"}, []uint{5, 3}},
+		"from":             {model.ArtSearch{From: &future}, []uint{}},
+		"to":               {model.ArtSearch{To: &future}, []uint{5, 3, 2, 1}},
+		"min likes":        {model.ArtSearch{MinLikes: 3}, []uint{5, 2}},
+		"selected":         {model.ArtSearch{Selected: true}, []uint{5}},
+	}
+	for name, c := range cases {
+		c.search.Limit = 10
+		arts, err := repo.SearchArts(ctx, c.search)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if ids := artIDs(arts); !reflect.DeepEqual(ids, c.expected) {
+			t.Errorf("%s: expected arts %v, got %v", name, c.expected, ids)
+		}
+	}
+
+	// cursor pages
+	search := model.ArtSearch{Limit: 2}
+	var pages [][]uint
+	for {
+		arts, err := repo.SearchArts(ctx, search)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(arts) == 0 {
+			break
+		}
+		pages = append(pages, artIDs(arts))
+		search.BeforeID = arts[len(arts)-1].ID
+	}
+	if expected := [][]uint{{5, 3}, {2, 1}}; !reflect.DeepEqual(pages, expected) {
+		t.Fatalf("expected pages %v, got %v", expected, pages)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if escaped := escapeLike(`50%_off\`); escaped != `50\%\_off\\` {
+		t.Fatalf("unexpected escaped string %s", escaped)
+	}
+}
+
+func artIDs(arts []model.Art) []uint {
+	ids := make([]uint, 0, len(arts))
+	for _, art := range arts {
+		ids = append(ids, art.ID)
+	}
+	return ids
+}
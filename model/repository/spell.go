@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SpellRepository struct {
@@ -20,7 +22,131 @@ func (dr *SpellRepository) GetLastSpell(ctx context.Context) (model.Spell, error
 	return spell, err
 }
 
+// Save saves spell with its tags (tags and spell_tags tables) in one transaction
 func (sr *SpellRepository) Save(ctx context.Context, spell model.Spell) (model.Spell, error) {
-	err := sr.db.Save(&spell).Error
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&spell).Error; err != nil {
+			return err
+		}
+		return saveSpellTags(tx, spell)
+	})
 	return spell, err
 }
+
+// GetSpellTags returns tags of spell from spell_tags in their order
+func (sr *SpellRepository) GetSpellTags(ctx context.Context, spellID uint) ([]string, error) {
+	var tags []string
+	err := sr.db.WithContext(ctx).
+		Model(&model.SpellTag{}).
+		Select("tags.name").
+		Joins("join tags on tags.id = spell_tags.tag_id").
+		Where("spell_tags.spell_id = ?", spellID).
+		Order("spell_tags.position").
+		Scan(&tags).Error
+	return tags, errors.Wrapf(err, "[spell_repository] failed to get tags of spell %d", spellID)
+}
+
+// GetTag finds tag by name (gorm.ErrRecordNotFound if no spell has this tag)
+func (sr *SpellRepository) GetTag(ctx context.Context, name string) (model.Tag, error) {
+	var tag model.Tag
+	err := sr.db.WithContext(ctx).Where("name = ?", name).First(&tag).Error
+	return tag, err
+}
+
+// CountSpellsWithTag - number of spells with tag
+func (sr *SpellRepository) CountSpellsWithTag(ctx context.Context, name string) (uint, error) {
+	var count uint
+	err := sr.db.WithContext(ctx).
+		Model(&model.SpellTag{}).
+		Select("count(distinct spell_tags.spell_id)").
+		Joins("join tags on tags.id = spell_tags.tag_id").
+		Where("tags.name = ?", name).
+		Scan(&count).Error
+	return count, errors.Wrapf(err, "[spell_repository] failed to count spells with tag %s", name)
+}
+
+// GetSpellIDsWithTags returns ids of spells, which have all given tags (newest first, ids less than beforeID, 0 - from newest)
+func (sr *SpellRepository) GetSpellIDsWithTags(ctx context.Context, names []string, beforeID uint, limit uint) ([]uint, error) {
+	if len(names) == 0 {
+		return []uint{}, nil
+	}
+	query := sr.db.WithContext(ctx).
+		Model(&model.SpellTag{}).
+		Select("spell_tags.spell_id").
+		Joins("join tags on tags.id = spell_tags.tag_id").
+		Where("tags.name in ?", names).
+		Group("spell_tags.spell_id").
+		Having("count(distinct tags.id) = ?", len(names)).
+		Order("spell_tags.spell_id desc").
+		Limit(int(limit))
+	if beforeID > 0 {
+		query = query.Where("spell_tags.spell_id < ?", beforeID)
+	}
+	var ids []uint
+	err := query.Scan(&ids).Error
+	return ids, errors.Wrapf(err, "[spell_repository] failed to get spells with tags %v", names)
+}
+
+/*
+BackfillSpellTags fills spell_tags of spells with id > afterID from their Spell.Tags (batch of limit spells).
+It is idempotent: spell_tags of spell are replaced. Returns last processed spell id and number of processed spells.
+*/
+func (sr *SpellRepository) BackfillSpellTags(ctx context.Context, afterID uint, limit uint) (uint, uint, error) {
+	var spells []model.Spell
+	err := sr.db.WithContext(ctx).
+		Unscoped().
+		Where("id > ?", afterID).
+		Order("id asc").
+		Limit(int(limit)).
+		Find(&spells).Error
+	if err != nil {
+		return afterID, 0, errors.Wrapf(err, "[spell_repository] failed to get spells after %d", afterID)
+	}
+	for _, spell := range spells {
+		err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return saveSpellTags(tx, spell)
+		})
+		if err != nil {
+			return afterID, 0, errors.Wrapf(err, "[spell_repository] failed to backfill tags of spell %d", spell.ID)
+		}
+		afterID = spell.ID
+	}
+	return afterID, uint(len(spells)), nil
+}
+
+// saveSpellTags replaces spell_tags of spell with tags from Spell.Tags, unknown tags are created
+func saveSpellTags(tx *gorm.DB, spell model.Spell) error {
+	if err := tx.Where("spell_id = ?", spell.ID).Delete(&model.SpellTag{}).Error; err != nil {
+		return errors.Wrap(err, "failed to delete old spell tags")
+	}
+	names := model.ParseTags(spell.Tags)
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags := make([]model.Tag, 0, len(names))
+	unique := make(map[string]bool, len(names))
+	for _, name := range names {
+		if !unique[name] {
+			unique[name] = true
+			tags = append(tags, model.Tag{Name: name})
+		}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+		return errors.Wrap(err, "failed to create tags")
+	}
+	var existing []model.Tag
+	if err := tx.Where("name in ?", names).Find(&existing).Error; err != nil {
+		return errors.Wrap(err, "failed to get tags")
+	}
+	ids := make(map[string]uint, len(existing))
+	for _, tag := range existing {
+		ids[tag.Name] = tag.ID
+	}
+
+	spellTags := make([]model.SpellTag, 0, len(names))
+	for position, name := range names {
+		spellTags = append(spellTags, model.SpellTag{SpellID: spell.ID, Position: uint(position), TagID: ids[name]})
+	}
+	return errors.Wrap(tx.Omit("Tag").Create(&spellTags).Error, "failed to create spell tags")
+}
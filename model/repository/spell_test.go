@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"reflect"
+	"testing"
+)
+
+func TestSaveSpellTags(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewSpellRepository(db)
+
+	first, err := repo.Save(ctx, model.Spell{Tags: "sun, moon,,sun"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// existing tags are not created again (on conflict do nothing), their ids are selected again
+	second, err := repo.Save(ctx, model.Spell{Tags: "moon,star"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := repo.GetSpellTags(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"sun", "moon", "sun"}; !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected tags %v, got %v", expected, tags)
+	}
+	tags, err = repo.GetSpellTags(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"moon", "star"}; !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected tags %v, got %v", expected, tags)
+	}
+
+	var total int64
+	if err := db.Model(&model.Tag{}).Count(&total).Error; err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Fatalf("expected 3 tags, got %d", total)
+	}
+	if count, err := repo.CountSpellsWithTag(ctx, "moon"); err != nil || count != 2 {
+		t.Fatalf("expected 2 spells with moon, got %d (err %v)", count, err)
+	}
+
+	// re-saved spell replaces its tags
+	first.Tags = "star"
+	if _, err := repo.Save(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err = repo.GetSpellTags(ctx, first.ID); err != nil || !reflect.DeepEqual(tags, []string{"star"}) {
+		t.Fatalf("expected replaced tags [star], got %v (err %v)", tags, err)
+	}
+	ids, err := repo.GetSpellIDsWithTags(ctx, []string{"star"}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []uint{second.ID, first.ID}; !reflect.DeepEqual(ids, expected) {
+		t.Fatalf("expected spells %v, got %v", expected, ids)
+	}
+}
+
+func TestBackfillSpellTags(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	repo := NewSpellRepository(db)
+
+	// historic spells were saved without spell_tags
+	for _, tags := range []string{"sun,moon", "moon", "", "star,sun"} {
+		if err := db.Create(&model.Spell{Tags: tags}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var spellTags int64
+	if err := db.Model(&model.SpellTag{}).Count(&spellTags).Error; err != nil || spellTags != 0 {
+		t.Fatalf("expected no spell tags before backfill, got %d (err %v)", spellTags, err)
+	}
+
+	backfill := func() {
+		var lastID, count uint
+		for batches := 0; ; batches++ {
+			var err error
+			lastID, count, err = repo.BackfillSpellTags(ctx, lastID, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count == 0 {
+				if batches != 2 {
+					t.Fatalf("expected 2 batches of 3 spells, got %d", batches)
+				}
+				return
+			}
+		}
+	}
+	backfill()
+	backfill() // repeated backfill does not duplicate anything
+
+	if err := db.Model(&model.SpellTag{}).Count(&spellTags).Error; err != nil || spellTags != 5 {
+		t.Fatalf("expected 5 spell tags after backfill, got %d (err %v)", spellTags, err)
+	}
+	if count, err := repo.CountSpellsWithTag(ctx, "sun"); err != nil || count != 2 {
+		t.Fatalf("expected 2 spells with sun, got %d (err %v)", count, err)
+	}
+	ids, err := repo.GetSpellIDsWithTags(ctx, []string{"sun", "moon"}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("expected 1 spell with sun and moon, got %v", ids)
+	}
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+/*
+artTags - one row for every tag of every published art (from normalized tables tags and spell_tags, old spells are filled by tags_backfill).
+Version filter is @version ("" - all versions), @published is model.ArtStatePublished.
+*/
+const artTags = `with art_tags as (
+	select distinct a.id as art_id, a.likes, s.version, t.name as tag
+	from arts a
+	join spells s on s.id = a.spell_id
+	join spell_tags st on st.spell_id = a.spell_id
+	join tags t on t.id = st.tag_id
+	where a.state = @published and (@version = '' or s.version = @version)
+)
+`
+
+// TagStatsRepository - aggregates over tags of arts (to curate next dictionary version)
+type TagStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewTagStatsRepository(db *gorm.DB) *TagStatsRepository {
+	return &TagStatsRepository{db}
+}
+
+// GetTagFrequency - most frequent tags
+func (r *TagStatsRepository) GetTagFrequency(ctx context.Context, version string, limit uint) ([]model.TagStat, error) {
+	var stats []model.TagStat
+	err := r.db.WithContext(ctx).Raw(artTags+`
+		select tag, count(*) as count from art_tags
+		group by tag
+		order by count desc, tag
+		limit @limit`,
+		map[string]interface{}{"published": model.ArtStatePublished, "version": version, "limit": limit},
+	).Scan(&stats).Error
+	return stats, errors.Wrap(err, "[tag_stats] failed to get tag frequency")
+}
+
+// GetTagFrequencyByVersion - most frequent tags of every version (limit per version)
+func (r *TagStatsRepository) GetTagFrequencyByVersion(ctx context.Context, limit uint) ([]model.TagVersionStat, error) {
+	var stats []model.TagVersionStat
+	err := r.db.WithContext(ctx).Raw(artTags+`
+		select version, tag, count from (
+			select version, tag, count(*) as count, row_number() over (partition by version order by count(*) desc, tag) as place
+			from art_tags
+			group by version, tag
+		) v
+		where place <= @limit
+		order by version, count desc, tag`,
+		map[string]interface{}{"published": model.ArtStatePublished, "version": "", "limit": limit},
+	).Scan(&stats).Error
+	return stats, errors.Wrap(err, "[tag_stats] failed to get tag frequency by version")
+}
+
+/*
+GetTagPairs - co-occurrence matrix (sparse, most frequent pairs first). tag != "" - only pairs with this tag.
+Pairs are joined inside of spell by primary key of spell_tags, so cost is linear by number of arts.
+*/
+func (r *TagStatsRepository) GetTagPairs(ctx context.Context, version string, tag string, limit uint) ([]model.TagPair, error) {
+	var pairs []model.TagPair
+	err := r.db.WithContext(ctx).Raw(`
+		select ta.name as tag_a, tb.name as tag_b, count(distinct a.id) as count
+		from arts a
+		join spells s on s.id = a.spell_id
+		join spell_tags sa on sa.spell_id = a.spell_id
+		join spell_tags sb on sb.spell_id = a.spell_id and sb.tag_id <> sa.tag_id
+		join tags ta on ta.id = sa.tag_id
+		join tags tb on tb.id = sb.tag_id
+		where a.state = @published
+			and ta.name < tb.name
+			and (@version = '' or s.version = @version)
+			and (@tag = '' or ta.name = @tag or tb.name = @tag)
+		group by ta.name, tb.name
+		order by count desc, ta.name, tb.name
+		limit @limit`,
+		map[string]interface{}{"published": model.ArtStatePublished, "version": version, "tag": tag, "limit": limit},
+	).Scan(&pairs).Error
+	return pairs, errors.Wrap(err, "[tag_stats] failed to get tag pairs")
+}
+
+// GetTagLikes - tags with most liked arts (average likes). Tags of less than minArts arts are skipped as noise
+func (r *TagStatsRepository) GetTagLikes(ctx context.Context, version string, minArts uint, limit uint) ([]model.TagLikes, error) {
+	var stats []model.TagLikes
+	err := r.db.WithContext(ctx).Raw(artTags+`
+		select tag, count(*) as arts, sum(likes) as likes, avg(likes)::float as avg_likes
+		from art_tags
+		group by tag
+		having count(*) >= @min_arts
+		order by avg_likes desc, arts desc, tag
+		limit @limit`,
+		map[string]interface{}{"published": model.ArtStatePublished, "version": version, "min_arts": minArts, "limit": limit},
+	).Scan(&stats).Error
+	return stats, errors.Wrap(err, "[tag_stats] failed to get tag likes")
+}
+
+// GetTagSelections - tags of arts, selected by lotteries, with share of selected arts among all arts with the tag
+func (r *TagStatsRepository) GetTagSelections(ctx context.Context, version string, limit uint) ([]model.TagSelectionStat, error) {
+	var stats []model.TagSelectionStat
+	err := r.db.WithContext(ctx).Raw(artTags+`,
+	selected as (
+		select distinct card_id from selections where deleted_at is null
+	)
+	select t.tag,
+		count(sel.card_id) as selected,
+		count(*) as total,
+		count(sel.card_id)::float / count(*) as share
+	from art_tags t
+	left join selected sel on sel.card_id = t.art_id
+	group by t.tag
+	having count(sel.card_id) > 0
+	order by selected desc, share desc, t.tag
+	limit @limit`,
+		map[string]interface{}{"published": model.ArtStatePublished, "version": version, "limit": limit},
+	).Scan(&stats).Error
+	return stats, errors.Wrap(err, "[tag_stats] failed to get tag selections")
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"reflect"
+	"testing"
+)
+
+func TestTagStats(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	createArt(t, db, model.Art{ID: 1, Likes: 2, Spell: model.Spell{Tags: "sun, moon", Version: "v1"}})
+	createArt(t, db, model.Art{ID: 2, Likes: 4, Spell: model.Spell{Tags: "sun,star", Version: "v1"}})
+	createArt(t, db, model.Art{ID: 3, Spell: model.Spell{Tags: "sun,moon,moon", Version: "v2"}}) // duplicate is counted once
+	createArt(t, db, model.Art{ID: 4, Spell: model.Spell{Tags: "", Version: "v2"}})
+	createArt(t, db, model.Art{ID: 5, State: model.ArtStatePainted, Spell: model.Spell{Tags: "sun,star", Version: "v1"}}) // not published
+	if err := db.Omit("Card", "Lottery").Create(&model.Selection{CardID: 2, LotteryID: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	repo := NewTagStatsRepository(db)
+
+	frequency, err := repo.GetTagFrequency(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagStat{{Tag: "sun", Count: 3}, {Tag: "moon", Count: 2}, {Tag: "star", Count: 1}}; !reflect.DeepEqual(frequency, expected) {
+		t.Fatalf("expected frequency %v, got %v", expected, frequency)
+	}
+	frequency, err = repo.GetTagFrequency(ctx, "v1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagStat{{Tag: "sun", Count: 2}}; !reflect.DeepEqual(frequency, expected) {
+		t.Fatalf("expected frequency of v1 %v, got %v", expected, frequency)
+	}
+
+	versions, err := repo.GetTagFrequencyByVersion(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagVersionStat{{Version: "v1", Tag: "sun", Count: 2}, {Version: "v2", Tag: "moon", Count: 1}}; !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("expected frequency by version %v, got %v", expected, versions)
+	}
+
+	pairs, err := repo.GetTagPairs(ctx, "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagPair{{TagA: "moon", TagB: "sun", Count: 2}, {TagA: "star", TagB: "sun", Count: 1}}; !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("expected pairs %v, got %v", expected, pairs)
+	}
+	pairs, err = repo.GetTagPairs(ctx, "", "star", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagPair{{TagA: "star", TagB: "sun", Count: 1}}; !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("expected pairs with star %v, got %v", expected, pairs)
+	}
+	pairs, err = repo.GetTagPairs(ctx, "v2", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagPair{{TagA: "moon", TagB: "sun", Count: 1}}; !reflect.DeepEqual(pairs, expected) {
+		t.Fatalf("expected pairs of v2 %v, got %v", expected, pairs)
+	}
+
+	likes, err := repo.GetTagLikes(ctx, "", 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagLikes{{Tag: "sun", Arts: 3, Likes: 6, AvgLikes: 2}, {Tag: "moon", Arts: 2, Likes: 2, AvgLikes: 1}}; !reflect.DeepEqual(likes, expected) {
+		t.Fatalf("expected likes %v, got %v", expected, likes)
+	}
+
+	selections, err := repo.GetTagSelections(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []model.TagSelectionStat{{Tag: "star", Selected: 1, Total: 1, Share: 1}, {Tag: "sun", Selected: 1, Total: 3, Share: 1.0 / 3}}; !reflect.DeepEqual(selections, expected) {
+		t.Fatalf("expected selections %v, got %v", expected, selections)
+	}
+}
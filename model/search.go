@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// ArtSearch - filters of arts search. Empty fields are not used
+type ArtSearch struct {
+	Query    string   // substring of any tag of art (case-insensitive)
+	TagsAll  []string // art has all these tags (tags are in lower case, tags are compared case-insensitive)
+	TagsAny  []string // art has at least one of these tags
+	TagsNone []string // art has none of these tags
+	Seed     *uint
+	Version  string
+	From     *time.Time
+	To       *time.Time
+	MinLikes uint
+	Selected bool // only arts selected by lotteries
+	BeforeID uint // cursor: arts with id less than this (0 - from newest)
+	Limit    uint
+}
@@ -1,12 +1,30 @@
 package model
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+	"time"
+)
 
 // Spell - is text command to make an artwork. Spell is a combination of picture caption, tags and seed.
 // Finally, Spell used by artist to make a picture.
 type Spell struct {
 	gorm.Model
-	Tags    string // additional tags to paint the picture (https://www.reddit.com/r/StableDiffusion/comments/y649yn/prompts_modifiers_to_get_midjourney_style_in/)
-	Seed    uint   // specified seed (seed is from 0 to 10 000 000 000)
-	Version string // in what environment made card (tags set, version on StableDiffusion etc.)
+	Tags        string // additional tags to paint the picture (https://www.reddit.com/r/StableDiffusion/comments/y649yn/prompts_modifiers_to_get_midjourney_style_in/)
+	Seed        uint   `gorm:"index"` // specified seed (seed is from 0 to 10 000 000 000)
+	Version     string // in what environment made card (tags set, version on StableDiffusion etc.)
+	EngineModel string // model of artist engine from version manifest (empty - artist default)
+
+	// generation parameters (taken from dictionary version). Defaults are values, which were hardcoded in artist before
+	NegativePrompt string
+	Sampler        string  // empty - artist default
+	Steps          uint    `gorm:"not null;default:50"`
+	CFGScale       float64 `gorm:"not null;default:0"` // 0 - artist default
+	Width          uint    `gorm:"not null;default:640"`
+	Height         uint    `gorm:"not null;default:960"`
+	Upscale        uint    `gorm:"not null;default:4"`
+
+	// provenance of the spell
+	DictionaryHash string    // sha256 of dictionary file (and its rules) of Version
+	SeedRaw        string    // raw entropy value, which gave Seed
+	SeedFrameTime  time.Time // time of frame, which gave SeedRaw
 }
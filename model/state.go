@@ -8,12 +8,18 @@ import (
 type CreationState struct {
 	NextArtID            uint
 	PreviousCardID       uint
+	ParentArtID          uint   // art is remix of this art (0 - original art)
+	Remix                string // kind of remix
 	Version              string
 	Seed                 uint
 	TagsCount            uint
 	Tags                 []string
-	LastCardPaintTime    uint // seconds
-	CurrentCardPaintTime uint // seconds
+	LastCardPaintTime    uint    // seconds
+	CurrentCardPaintTime uint    // seconds
+	PaintStep            uint    // current diffusion step (0 - engine doesn't report progress)
+	PaintTotalSteps      uint    // diffusion steps of painting
+	PaintProgress        float64 // percent of painting, 0..100
+	PaintPreview         string  // base64 jpeg of intermediate latent (empty - previews are disabled)
 	CardID               uint
 	EnjoyTime            uint
 	CurrentEnjoyTime     uint
@@ -51,6 +57,28 @@ type EntropyState struct {
 	ImagesEncoded map[string]string      // base64 encoded images (source, noise, entropy, choice)
 	Entropy       EntropyValue
 	Choice        EntropyValue
+	RawEntropy    EntropyValue // thresholded bits of entropy picture before conditioning (same as Entropy without conditioning)
+	RawChoice     EntropyValue // thresholded bits of choice picture before conditioning
+}
+
+// EntropyHealth - результат статистических проверок потока энтропии (публикуется в канал entropy_health)
+type EntropyHealth struct {
+	Timestamp time.Time
+	Healthy   bool
+	Streams   []EntropyStreamHealth
+}
+
+type EntropyStreamHealth struct {
+	Name               string // entropy or choice
+	Healthy            bool
+	WarmingUp          bool // not enough samples yet, tests are not calculated
+	Samples            int
+	Monobit            float64 // p-value
+	Runs               float64 // p-value
+	ChiSquare          float64 // statistic over 256 byte buckets
+	RepetitionCount    int     // current number of identical values in a row
+	AdaptiveProportion int     // occurrences of the first byte in current window
+	Failures           []string
 }
 
 const (
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+/*
+Tag - unique tag of all spells (dictionary entries are strings, so tag is identified by its name).
+Search compares lower(name) and finds substring with ilike, so name has expression and trigram indexes
+(trigram index needs postgres extension pg_trgm, see TagsExtension).
+*/
+type Tag struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Name      string `gorm:"not null;uniqueIndex;index:idx_tags_name_lower,expression:lower(name);index:idx_tags_name_trgm,type:gin,expression:name gin_trgm_ops"`
+}
+
+// TagsExtension - sql of postgres extension for trigram index of tags, it is executed before auto-migration
+const TagsExtension = "create extension if not exists pg_trgm"
+
+// SpellTag - tag of spell on its position in Spell.Tags (the same tag can be twice if dictionary allows duplicates)
+type SpellTag struct {
+	SpellID  uint `gorm:"primaryKey;autoIncrement:false"`
+	Position uint `gorm:"primaryKey;autoIncrement:false"`
+	TagID    uint `gorm:"not null;index"`
+	Tag      Tag
+}
+
+// ParseTags splits comma string of Spell.Tags
+func ParseTags(tags string) []string {
+	result := make([]string, 0)
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
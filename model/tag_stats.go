@@ -0,0 +1,37 @@
+package model
+
+// TagStat - how many arts have the tag
+type TagStat struct {
+	Tag   string
+	Count uint
+}
+
+// TagVersionStat - how many arts of version have the tag
+type TagVersionStat struct {
+	Version string
+	Tag     string
+	Count   uint
+}
+
+// TagPair - how many arts have both tags (TagA < TagB)
+type TagPair struct {
+	TagA  string
+	TagB  string
+	Count uint
+}
+
+// TagLikes - likes of arts with the tag
+type TagLikes struct {
+	Tag      string
+	Arts     uint
+	Likes    uint
+	AvgLikes float64
+}
+
+// TagSelectionStat - how many arts with the tag were selected by lotteries (from Total arts with the tag)
+type TagSelectionStat struct {
+	Tag      string
+	Selected uint
+	Total    uint
+	Share    float64 // Selected / Total
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/blackjack/webcam"
+)
+
+// Raw frame format (/raw): header + frame bytes as camera gives them (YUYV - 2 bytes per pixel), all numbers big-endian.
+//
+//	magic     [4]byte  "ARAW"
+//	width     uint32
+//	height    uint32
+//	fourcc    uint32   V4L2 pixel format (0x56595559 - YUYV)
+//	timestamp int64    unix nanoseconds, when frame was read from camera
+//	length    uint32   bytes of frame after header
+const (
+	RawMagic      = "ARAW"
+	RawHeaderSize = 4 + 4 + 4 + 4 + 8 + 4
+
+	fpsWindow      = time.Second * 10
+	rawWaitTimeout = time.Second * 5 // same as camera frame timeout
+)
+
+// FrameStatus is /status response
+type FrameStatus struct {
+	Device    string
+	Format    string
+	FourCC    uint32
+	Width     uint32
+	Height    uint32
+	FPS       float64
+	Frames    uint64 // frames read from camera
+	Dropped   uint64 // frames not encoded because encoder was busy
+	LastFrame time.Time
+}
+
+// latestFrame keeps last raw frame, last encoded jpeg and camera statistics for http handlers
+type latestFrame struct {
+	mutex     sync.RWMutex
+	status    FrameStatus
+	raw       []byte
+	next      chan struct{} // closed when new raw frame is set
+	jpeg      []byte
+	fpsStart  time.Time
+	fpsFrames uint64
+}
+
+func newLatestFrame(device string, format webcam.PixelFormat, formatName string, w, h uint32) *latestFrame {
+	return &latestFrame{
+		status: FrameStatus{
+			Device: device,
+			Format: formatName,
+			FourCC: uint32(format),
+			Width:  w,
+			Height: h,
+		},
+		next:     make(chan struct{}),
+		fpsStart: time.Now(),
+	}
+}
+
+func (l *latestFrame) setRaw(frame []byte, dropped bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// camera buffer will be reused by driver, so frame is copied
+	if len(l.raw) != len(frame) {
+		l.raw = make([]byte, len(frame))
+	}
+	copy(l.raw, frame)
+	l.status.LastFrame = time.Now()
+	l.status.Frames++
+	if dropped {
+		l.status.Dropped++
+	}
+
+	l.fpsFrames++
+	if d := time.Since(l.fpsStart); d > fpsWindow {
+		l.status.FPS = float64(l.fpsFrames) / d.Seconds()
+		l.fpsStart = time.Now()
+		l.fpsFrames = 0
+	}
+
+	// wake up /raw clients waiting for this frame
+	close(l.next)
+	l.next = make(chan struct{})
+}
+
+func (l *latestFrame) setJpeg(jpeg []byte) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.jpeg = jpeg
+}
+
+func (l *latestFrame) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	l.mutex.RLock()
+	img := l.jpeg
+	l.mutex.RUnlock()
+	if img == nil {
+		http.Error(w, "no frame yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	if _, err := w.Write(img); err != nil {
+		log.Println(err)
+	}
+}
+
+// handleRaw waits for the next frame from camera (like "/"), so every response is a new frame
+func (l *latestFrame) handleRaw(w http.ResponseWriter, r *http.Request) {
+	l.mutex.RLock()
+	next := l.next
+	l.mutex.RUnlock()
+	select {
+	case <-next:
+	case <-r.Context().Done():
+		return
+	case <-time.After(rawWaitTimeout):
+		http.Error(w, "no fresh frame", http.StatusServiceUnavailable)
+		return
+	}
+
+	l.mutex.RLock()
+	buf := make([]byte, RawHeaderSize+len(l.raw))
+	copy(buf[0:4], RawMagic)
+	binary.BigEndian.PutUint32(buf[4:8], l.status.Width)
+	binary.BigEndian.PutUint32(buf[8:12], l.status.Height)
+	binary.BigEndian.PutUint32(buf[12:16], l.status.FourCC)
+	binary.BigEndian.PutUint64(buf[16:24], uint64(l.status.LastFrame.UnixNano()))
+	binary.BigEndian.PutUint32(buf[24:28], uint32(len(l.raw)))
+	copy(buf[RawHeaderSize:], l.raw)
+	l.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := w.Write(buf); err != nil {
+		log.Println(err)
+	}
+}
+
+func (l *latestFrame) handleStatus(w http.ResponseWriter, r *http.Request) {
+	l.mutex.RLock()
+	status := l.status
+	l.mutex.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Println(err)
+	}
+}
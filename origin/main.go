@@ -130,6 +130,7 @@ FMT:
 
 	}
 	fmt.Fprintf(os.Stderr, "Resulting image format: %s %dx%d\n", format_desc[f], w, h)
+	latest := newLatestFrame(*dev, f, format_desc[f], w, h)
 
 	// start streaming
 	err = cam.StartStreaming()
@@ -143,8 +144,8 @@ FMT:
 		fi   chan []byte        = make(chan []byte)
 		back chan struct{}      = make(chan struct{})
 	)
-	go encodeToImage(cam, back, fi, li, w, h, f)
-	go httpServer(*addr, li)
+	go encodeToImage(cam, back, fi, li, w, h, f, latest)
+	go httpServer(*addr, li, latest)
 
 	timeout := uint32(5) //5 seconds
 	start := time.Now()
@@ -184,16 +185,19 @@ FMT:
 				}
 			}
 
+			dropped := false
 			select {
 			case fi <- frame:
 				<-back
 			default:
+				dropped = true // encoder is busy
 			}
+			latest.setRaw(frame, dropped)
 		}
 	}
 }
 
-func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li chan *bytes.Buffer, w, h uint32, format webcam.PixelFormat) {
+func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li chan *bytes.Buffer, w, h uint32, format webcam.PixelFormat, latest *latestFrame) {
 
 	var (
 		frame []byte
@@ -229,6 +233,7 @@ func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li cha
 			log.Fatal(err)
 			return
 		}
+		latest.setJpeg(buf.Bytes())
 
 		const N = 50
 		// broadcast image up to N ready clients
@@ -248,7 +253,7 @@ func encodeToImage(wc *webcam.Webcam, back chan struct{}, fi chan []byte, li cha
 	}
 }
 
-func httpServer(addr string, li chan *bytes.Buffer) {
+func httpServer(addr string, li chan *bytes.Buffer, latest *latestFrame) {
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		//log.Println("connect from", r.RemoteAddr, r.URL)
 		if r.URL.Path != "/" {
@@ -302,5 +307,11 @@ func httpServer(addr string, li chan *bytes.Buffer) {
 		}
 	})
 
+	// latest frame without waiting for the next one
+	http.HandleFunc("/snapshot", latest.handleSnapshot)
+	// latest frame without compression (see frames.go for format)
+	http.HandleFunc("/raw", latest.handleRaw)
+	http.HandleFunc("/status", latest.handleStatus)
+
 	log.Fatal(http.ListenAndServe(addr, nil))
 }
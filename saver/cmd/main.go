@@ -35,6 +35,7 @@ func main() {
 	log.Info().Msg("service gate started")
 	svr := saver.NewSaver(res.GetEnv().ArtsPath, res.GetEnv().UnityPath, res.GetEnv().FullSizePath)
 	uploadHandler := handler.NewUploadHandler(svr)
+	fullsizeHandler := handler.NewFullsizeHandler(svr)
 
 	go func() {
 		r := gin.Default()
@@ -48,6 +49,7 @@ func main() {
 		r.POST("/upload_art", uploadHandler.Handle)
 		r.POST("/upload_unity", uploadHandler.HandleUnity)
 		r.POST("/upload_fullsize", uploadHandler.HandleFullsize)
+		r.GET("/fullsize/:id", fullsizeHandler.Handle)
 		if err := r.Run("0.0.0.0:" + res.GetEnv().HttpPort); err != nil {
 			log.Fatal().Err(err).Send()
 		}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+type fullsizeReader interface {
+	GetFullsizeArt(artID uint) ([]byte, error)
+}
+
+// FullsizeHandler gives full-size art back to soul (soul finishes creation of stuck arts with it)
+type FullsizeHandler struct {
+	reader fullsizeReader
+}
+
+func NewFullsizeHandler(reader fullsizeReader) *FullsizeHandler {
+	return &FullsizeHandler{reader}
+}
+
+func (h *FullsizeHandler) Handle(c *gin.Context) {
+	artID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "id must be integer")
+		return
+	}
+	data, err := h.reader.GetFullsizeArt(uint(artID))
+	if errors.Is(err, os.ErrNotExist) {
+		c.String(http.StatusNotFound, "not found")
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("[fullsize] failed to read art %d", artID)
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "image/jpeg", data)
+}
@@ -87,6 +87,12 @@ func (h *Saver) SaveFullsizeArt(cardID uint, data []byte) error {
 	return err
 }
 
+// GetFullsizeArt reads full-size art (os.ErrNotExist if art was not uploaded)
+func (h *Saver) GetFullsizeArt(cardID uint) ([]byte, error) {
+	idFolder := fmt.Sprintf("%d", model.GetCardThousand(cardID))
+	return os.ReadFile(path.Join(h.fullsizePath, idFolder, fmt.Sprintf("art-%d.jpg", cardID)))
+}
+
 func (h *Saver) saveFile(folder string, filename string, data []byte) error {
 	folderPath := path.Join(folder)
 	if err := os.MkdirAll(folderPath, os.ModePerm); err != nil {
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/artchitector/artchitect/memory"
+	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/model/repository"
+	"github.com/artchitector/artchitect/soul/core/reconciler"
+	"github.com/artchitector/artchitect/soul/core/saver"
+	"github.com/artchitector/artchitect/soul/core/spool"
+	"github.com/artchitector/artchitect/soul/resources"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"os"
+	"os/signal"
+	"time"
+)
+
+/*
+arts_report shows consistency of art creation: number of arts in every state, published arts without upload flags
+and arts stuck in painted/stored states. With -fix it runs one pass of reconciler (published arts are not announced,
+soul does it when RECONCILER_ENABLED=true). -fix runs only on host of soul, where SPOOL_DIR exists (arts with images
+in spool are left to uploader of soul), -keep-painted leaves painted arts as they are.
+
+	go run ./cmd/arts_report -stuck 10m -limit 100
+	go run ./cmd/arts_report -fix
+	go run ./cmd/arts_report -fix -keep-painted
+*/
+func main() {
+	stuck := flag.Duration("stuck", reconciler.StuckTimeout, "art is stuck if its state is unchanged for this time")
+	limit := flag.Uint("limit", 100, "max stuck arts in report")
+	fix := flag.Bool("fix", false, "run reconciler once")
+	keepPainted := flag.Bool("keep-painted", false, "with -fix: do not touch painted arts")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: "2006-01-02T15:04:05"})
+
+	res := resources.InitDBResources()
+	artsRepo := repository.NewCardRepository(res.GetDB(), nil)
+
+	counts, err := artsRepo.CountArtsByState(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	fmt.Println("arts by state:")
+	for _, count := range counts {
+		fmt.Printf("  %-10s %d\n", count.State, count.Total)
+	}
+	inconsistent, err := artsRepo.CountPublishedWithoutUploads(ctx)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	fmt.Printf("published without upload flags: %d\n", inconsistent)
+
+	arts, err := artsRepo.GetStuckArts(ctx, time.Now().Add(-*stuck), *limit)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	fmt.Printf("stuck arts (unchanged for %s): %d\n", *stuck, len(arts))
+	for _, art := range arts {
+		fmt.Printf("  #%d %-9s updated %s (%s ago), storage=%t, memory=%t\n",
+			art.ID, art.State, art.UpdatedAt.Format(time.RFC3339), time.Since(art.UpdatedAt).Round(time.Second),
+			art.UploadedToStorage, art.UploadedToMemory)
+	}
+
+	if !*fix {
+		return
+	}
+	sav := saver.NewSaver(res.GetEnv().MemorySaverURL, res.GetEnv().StorageSaverURL)
+	mmr := memory.NewMemory(res.GetEnv().MemoryHost, nil)
+	results, err := reconcile(ctx, artsRepo, sav, mmr, res.GetEnv().SpoolDir, *keepPainted)
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+	fmt.Printf("reconciled %d arts:\n", len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Printf("  #%d %-9s %s: %s\n", result.ArtID, result.State, result.Action, result.Err)
+		} else {
+			fmt.Printf("  #%d %-9s %s\n", result.ArtID, result.State, result.Action)
+		}
+	}
+}
+
+type artRepository interface {
+	GetStuckArts(ctx context.Context, before time.Time, limit uint) ([]model.Art, error)
+	SetArtState(ctx context.Context, artID uint, state string) error
+	DeleteArt(ctx context.Context, artID uint) error
+	GetArt(ctx context.Context, ID uint) (model.Art, error)
+}
+
+type imageSaver interface {
+	GetFullsize(ctx context.Context, artID uint) ([]byte, error)
+	SaveArt(ctx context.Context, artID uint, imageData []byte) error
+	SaveFullsize(ctx context.Context, artID uint, imageData []byte) error
+}
+
+type memoryReader interface {
+	DownloadImage(ctx context.Context, cardID uint, size string) ([]byte, error)
+}
+
+// reconcile runs one pass of reconciler. Arts with images in spool of soul are left to its uploader
+func reconcile(
+	ctx context.Context,
+	artsRepo artRepository,
+	sav imageSaver,
+	mmr memoryReader,
+	spoolDir string,
+	keepPainted bool,
+) ([]reconciler.Result, error) {
+	// spool is checked before NewSpool, which creates missing dir
+	if info, err := os.Stat(spoolDir); err != nil || !info.IsDir() {
+		return nil, errors.Errorf("[arts_report] spool %s is not found, -fix runs only on host of soul", spoolDir)
+	}
+	spl, err := spool.NewSpool(spoolDir)
+	if err != nil {
+		return nil, err
+	}
+	rcn := reconciler.NewReconciler(artsRepo, sav, mmr, spool.NewUploader(spl, sav, artsRepo, nil, 0), nil)
+	if keepPainted {
+		rcn.KeepPainted()
+	}
+	return rcn.Reconcile(ctx)
+}
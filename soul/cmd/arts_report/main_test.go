@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/memory"
+	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/soul/core/reconciler"
+	"github.com/artchitector/artchitect/soul/core/saver"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testRepository struct {
+	arts    []model.Art
+	deleted []uint
+}
+
+func (r *testRepository) GetStuckArts(ctx context.Context, before time.Time, limit uint) ([]model.Art, error) {
+	return r.arts, nil
+}
+
+func (r *testRepository) SetArtState(ctx context.Context, artID uint, state string) error {
+	return nil
+}
+
+func (r *testRepository) DeleteArt(ctx context.Context, artID uint) error {
+	r.deleted = append(r.deleted, artID)
+	return nil
+}
+
+func (r *testRepository) GetArt(ctx context.Context, ID uint) (model.Art, error) {
+	return model.Art{ID: ID}, nil
+}
+
+// testSaver - storage without any full-size image
+type testSaver struct{}
+
+func (s testSaver) GetFullsize(ctx context.Context, artID uint) ([]byte, error) {
+	return nil, saver.ErrNotFound
+}
+
+func (s testSaver) SaveArt(ctx context.Context, artID uint, imageData []byte) error {
+	return nil
+}
+
+func (s testSaver) SaveFullsize(ctx context.Context, artID uint, imageData []byte) error {
+	return nil
+}
+
+type testMemory struct{}
+
+func (m testMemory) DownloadImage(ctx context.Context, cardID uint, size string) ([]byte, error) {
+	return nil, memory.ErrNotFound
+}
+
+// TestReconcileEmptySpool - empty spool is drained spool, stuck painted art without full-size image is orphan
+func TestReconcileEmptySpool(t *testing.T) {
+	ctx := context.Background()
+	repo := &testRepository{arts: []model.Art{{ID: 1, State: model.ArtStatePainted}}}
+	results, err := reconcile(ctx, repo, testSaver{}, testMemory{}, t.TempDir(), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(results) != 1 || results[0].Action != reconciler.ActionDeleted || len(repo.deleted) != 1 || repo.deleted[0] != 1 {
+		t.Fatalf("painted art without image must be deleted: %+v, deleted %v", results, repo.deleted)
+	}
+
+	repo = &testRepository{arts: []model.Art{{ID: 1, State: model.ArtStatePainted}}}
+	results, err = reconcile(ctx, repo, testSaver{}, testMemory{}, t.TempDir(), true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if results[0].Action != reconciler.ActionSkipped || len(repo.deleted) != 0 {
+		t.Fatalf("painted art must be kept with -keep-painted: %+v", results)
+	}
+
+	if _, err := reconcile(ctx, repo, testSaver{}, testMemory{}, filepath.Join(t.TempDir(), "missing"), false); err == nil {
+		t.Fatalf("expected error without spool dir")
+	}
+}
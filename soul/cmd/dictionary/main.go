@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/artchitector/artchitect/soul/core/entropy"
+	"github.com/artchitector/artchitect/soul/core/speller"
+	"github.com/rs/zerolog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const usage = `dictionary - check tag dictionaries before release (without running soul)
+
+  dictionary lint [-versions dir] <file.yaml>...  validate files, exit code 1 on errors
+                                                  (comma in released dictionary of manifest dir is a warning)
+  dictionary diff <old.yaml> <new.yaml>           changes of categories, tags, weights, rules and params
+  dictionary preview [-n 10] [-seed 1] <file.yaml> sample spells made by deterministic entropy
+`
+
+func main() {
+	zerolog.SetGlobalLevel(zerolog.WarnLevel) // speller logs re-draws with info level
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	var code int
+	switch os.Args[1] {
+	case "lint":
+		code = lint(os.Args[2:])
+	case "diff":
+		code = diff(os.Args[2:])
+	case "preview":
+		code = preview(os.Args[2:])
+	default:
+		fmt.Print(usage)
+		code = 2
+	}
+	os.Exit(code)
+}
+
+func lint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	versions := flags.String("versions", "files/versions", "manifest directory of released versions")
+	_ = flags.Parse(args)
+	files := flags.Args()
+	if len(files) == 0 {
+		fmt.Print(usage)
+		return 2
+	}
+	released, err := speller.ReleasedDictionaries(*versions)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	code := 0
+	for _, file := range files {
+		path, err := filepath.Abs(file)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		dictionary, issues := speller.LintDictionary(file, released[path])
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", file, issue)
+			if issue.Level == speller.LintError {
+				code = 1
+			}
+		}
+		fmt.Printf("%s: %d categories, %d tags, longest prompt ~%d tokens, %d issues\n",
+			file, len(dictionary.Categories), dictionary.TotalTags(), dictionary.MaxPromptTokens(), len(issues))
+	}
+	return code
+}
+
+func diff(files []string) int {
+	if len(files) != 2 {
+		fmt.Print(usage)
+		return 2
+	}
+	old, err := speller.LoadDictionary(files[0])
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	new, err := speller.LoadDictionary(files[1])
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	changes := speller.DiffDictionaries(old, new)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	fmt.Printf("%d changes, tags %d -> %d\n", len(changes), old.TotalTags(), new.TotalTags())
+	return 0
+}
+
+func preview(args []string) int {
+	flags := flag.NewFlagSet("preview", flag.ExitOnError)
+	count := flags.Uint("n", 10, "number of spells")
+	seed := flags.Int64("seed", 1, "seed of deterministic entropy (the same seed gives the same spells)")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Print(usage)
+		return 2
+	}
+	file := flags.Arg(0)
+	dictionary, err := speller.LoadDictionary(file)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	e := entropy.NewEntropy(entropy.NewPrngSource(*seed), entropy.NewHealth(nil), false, nil)
+	s := speller.NewSpeller(nil, e, nil, nil)
+	version := strings.TrimSuffix(filepath.Base(file), ".yaml")
+	for i := uint(0); i < *count; i++ {
+		spell, err := s.PreviewSpell(context.Background(), version, dictionary)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		tokens := speller.EstimateTokens(spell.Tags)
+		fmt.Printf("#%d seed=%d steps=%d sampler=%q cfg=%g ~%d tokens\n  %s\n", i+1, spell.Seed, spell.Steps, spell.Sampler, spell.CFGScale, tokens, spell.Tags)
+	}
+	return 0
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/artchitector/artchitect/soul/core/heart"
 	"github.com/artchitector/artchitect/soul/core/lottery"
 	merciful2 "github.com/artchitector/artchitect/soul/core/merciful"
+	"github.com/artchitector/artchitect/soul/core/reconciler"
 	"github.com/artchitector/artchitect/soul/core/saver"
 	spellerService "github.com/artchitector/artchitect/soul/core/speller"
+	"github.com/artchitector/artchitect/soul/core/spool"
 	"github.com/artchitector/artchitect/soul/core/unifier"
 	"github.com/artchitector/artchitect/soul/core/watermark"
 	notifier2 "github.com/artchitector/artchitect/soul/notifier"
@@ -51,13 +53,52 @@ func main() {
 
 	// Entropy reader + (lightmaster+gatekeeper)
 	gk := entropy.NewGatekeeper(res.GetRedises(), notifier)
-	lightMaster := entropy.NewLightmaster(res.GetWebcam(), gk)
+	health := entropy.NewHealth(notifier)
+	var recorder *entropy.Recorder
+	if res.GetEnv().EntropyRecordDir != "" {
+		var err error
+		if recorder, err = entropy.NewRecorder(
+			res.GetEnv().EntropyRecordDir, res.GetEnv().EntropyRecordMaxSize, res.GetEnv().EntropyRecordMaxAge,
+		); err != nil {
+			log.Fatal().Err(err).Msgf("[main] failed to init entropy recorder")
+		}
+	}
+	pipelineConfig := entropy.DefaultPipelineConfig()
+	if res.GetEnv().EntropyPipeline != "" {
+		var err error
+		if pipelineConfig, err = entropy.LoadPipelineConfig(res.GetEnv().EntropyPipeline); err != nil {
+			log.Fatal().Err(err).Msgf("[main] failed to load lightmaster pipeline config")
+		}
+	}
+	var entropySource entropy.EntropySource
+	switch res.GetEnv().EntropySource {
+	case resources.EntropySourceWebcam:
+		entropySource = entropy.NewLightmaster(res.GetWebcam(), gk, recorder, health, pipelineConfig)
+	case resources.EntropySourceV4L2:
+		entropySource = entropy.NewLightmaster(res.GetV4L2Camera(), gk, recorder, health, pipelineConfig)
+	case resources.EntropySourceFrames:
+		entropySource = entropy.NewLightmaster(res.GetFramesDirectory(), gk, recorder, health, pipelineConfig)
+	case resources.EntropySourceUrandom:
+		entropySource = entropy.NewUrandomSource()
+	case resources.EntropySourcePrng:
+		entropySource = entropy.NewPrngSource(res.GetEnv().EntropySeed)
+	case resources.EntropySourceJournal:
+		journalSource, err := entropy.NewJournalSource(res.GetEnv().EntropyJournalDir)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[main] failed to init journal replay")
+		}
+		entropySource = journalSource
+	default:
+		log.Fatal().Msgf("[main] unknown entropy source %s", res.GetEnv().EntropySource)
+	}
+	log.Info().Msgf("[main] entropy source: %s", res.GetEnv().EntropySource)
 	go func() {
-		if err := lightMaster.StartEntropyReading(ctx); err != nil {
-			log.Fatal().Err(err).Msgf("[CRITICAL MALFUNCTION] lightmaster died")
+		if err := entropySource.StartEntropyReading(ctx); err != nil {
+			log.Fatal().Err(err).Msgf("[CRITICAL MALFUNCTION] entropy source died")
 		}
 	}()
-	entrp := entropy.NewEntropy(lightMaster)
+	decisionRepo := repository.NewDecisionRepository(res.GetDB())
+	entrp := entropy.NewEntropy(entropySource, health, res.GetEnv().EntropyHealthStrict, decisionRepo)
 
 	// repositoties
 	artsRepo := repository.NewCardRepository(res.GetDB(), entrp)
@@ -68,16 +109,39 @@ func main() {
 	unityRepo := repository.NewUnityRepository(res.GetDB())
 
 	// speller+artist+creator
-	speller := spellerService.NewSpeller(spellRepo, entrp, notifier)
+	registry, err := spellerService.NewRegistry(res.GetEnv().VersionsDir)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("[main] failed to load versions registry")
+	}
+	go registry.Watch(ctx)
+	speller := spellerService.NewSpeller(spellRepo, entrp, notifier, registry)
 	var engine artistService.EngineContract
 	if res.GetEnv().UseFakeArtist {
 		engine = engine2.NewFakeEngine(res.GetEnv().FakeGenerationTime)
+	} else if res.GetEnv().ArtistBackends != "" {
+		routerConfig, err := engine2.LoadRouterConfig(res.GetEnv().ArtistBackends)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[main] failed to load artist backends")
+		}
+		router, err := engine2.NewRouterFromConfig(routerConfig, res.GetEnv().FakeGenerationTime)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[main] failed to make artist router")
+		}
+		go router.Run(ctx)
+		engine = router
 	} else {
-		engine = engine2.NewArtistEngine(res.GetEnv().ArtistURL)
+		engine = engine2.NewArtistEngine(res.GetEnv().ArtistURL, engine2.DefaultTimeout, res.GetEnv().ArtistAsync)
 	}
 	sav := saver.NewSaver(res.GetEnv().MemorySaverURL, res.GetEnv().StorageSaverURL)
+	// spool keeps images until uploader sends them to storage and memory
+	spl, err := spool.NewSpool(res.GetEnv().SpoolDir)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("[main] failed to open spool")
+	}
+	uploader := spool.NewUploader(spl, sav, artsRepo, notifier, res.GetEnv().PrehotDelay)
+	go uploader.Run(ctx)
 	watermarkMaker := watermark.NewWatermark()
-	artist := artistService.NewArtist(engine, artsRepo, notifier, watermarkMaker, sav)
+	artist := artistService.NewArtist(engine, artsRepo, notifier, watermarkMaker, uploader, res.GetEnv().ArtistPreviews)
 
 	// memory (save images to memory-server)
 	mmr := memory.NewMemory(res.GetEnv().MemoryHost, nil)
@@ -108,14 +172,13 @@ func main() {
 		unfr,
 		artsRepo,
 		res.GetEnv().ArtTotalTime,
-		res.GetEnv().PrehotDelay,
 	)
 
 	// lottery runner
 	runner := lottery.NewRunner(lotteryRepo, selectionRepo, artsRepo, entrp, notifier)
 
 	// merciful
-	merciful := merciful2.NewMerciful(prayRepo, creator, notifier)
+	merciful := merciful2.NewMerciful(prayRepo, artsRepo, creator, notifier)
 
 	heartStateOperator := heart.NewHeartState(notifier, artsRepo, 4) // 4 dreams
 	go func() {
@@ -151,6 +214,12 @@ func main() {
 		}()
 	}
 
+	// reconciler finishes arts, which creation was interrupted
+	if res.GetEnv().ReconcilerEnabled {
+		rcnclr := reconciler.NewReconciler(artsRepo, sav, mmr, uploader, notifier)
+		go rcnclr.Run(ctx)
+	}
+
 	//uw := unity_worker.NewUnityWorker(artsRepo, unityRepo)
 	//uw.Work(ctx)
 
@@ -170,5 +239,10 @@ mainFor:
 		}
 	}
 
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Error().Err(err).Msgf("[main] failed to close entropy recorder")
+		}
+	}
 	log.Info().Msg("[main] soul.Setup finished")
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/artchitector/artchitect/model/repository"
+	"github.com/artchitector/artchitect/soul/resources"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"os"
+	"os/signal"
+)
+
+/*
+tags_backfill parses Spell.Tags of all historic spells into tags and spell_tags tables.
+New spells get their tags in SpellRepository.Save, so command is needed once (it is idempotent and can be restarted from -after).
+
+	go run ./cmd/tags_backfill -after 0 -batch 1000
+*/
+func main() {
+	after := flag.Uint("after", 0, "start from spells with id greater than this")
+	batch := flag.Uint("batch", 1000, "spells in one batch")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: "2006-01-02T15:04:05"})
+
+	res := resources.InitDBResources()
+	spellRepo := repository.NewSpellRepository(res.GetDB())
+
+	lastID := *after
+	var total uint
+	for ctx.Err() == nil {
+		var count uint
+		var err error
+		lastID, count, err = spellRepo.BackfillSpellTags(ctx, lastID, *batch)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[tags_backfill] failed, restart with -after %d", lastID)
+		}
+		if count == 0 {
+			break
+		}
+		total += count
+		log.Info().Msgf("[tags_backfill] processed %d spells, last spell id=%d", total, lastID)
+	}
+	log.Info().Msgf("[tags_backfill] done: %d spells, last spell id=%d", total, lastID)
+}
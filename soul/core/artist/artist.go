@@ -3,18 +3,22 @@ package artist
 import (
 	"bytes"
 	"context"
-	"fmt"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"github.com/artchitector/artchitect/model"
 	"github.com/artchitector/artchitect/resizer"
+	"github.com/artchitector/artchitect/soul/core/artist/engine"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"image"
 	"image/jpeg"
+	"sync"
 	"time"
 )
 
 type EngineContract interface {
-	GetImage(ctx context.Context, spell model.Spell) (image.Image, error)
+	GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error)
 }
 
 type notifier interface {
@@ -23,6 +27,7 @@ type notifier interface {
 
 type watermark interface {
 	AddArtWatermark(originalImage image.Image, artID uint) (image.Image, error)
+	Version() string
 }
 
 type artRepository interface {
@@ -31,9 +36,9 @@ type artRepository interface {
 	DeleteArt(ctx context.Context, artID uint) error
 }
 
-type saver interface {
-	SaveArt(ctx context.Context, artID uint, imageData []byte) error
-	SaveFullsize(ctx context.Context, artID uint, imageData []byte) error
+// uploader keeps images in local spool and uploads them to storage and memory (spool.Uploader)
+type uploader interface {
+	Store(ctx context.Context, artID uint, fullsize []byte, f []byte) error
 }
 
 type Artist struct {
@@ -41,11 +46,12 @@ type Artist struct {
 	artRepo   artRepository
 	notifier  notifier
 	watermark watermark
-	saver     saver
+	uploader  uploader
+	previews  bool // send intermediate latents of painting to creation channel
 }
 
-func NewArtist(engine EngineContract, artRepository artRepository, notifier notifier, watermark watermark, saver saver) *Artist {
-	return &Artist{engine, artRepository, notifier, watermark, saver}
+func NewArtist(engine EngineContract, artRepository artRepository, notifier notifier, watermark watermark, uploader uploader, previews bool) *Artist {
+	return &Artist{engine, artRepository, notifier, watermark, uploader, previews}
 }
 
 func (a *Artist) GetArt(
@@ -62,26 +68,65 @@ func (a *Artist) GetArt(
 	}
 
 	paintStart := time.Now()
+	var stateMutex sync.Mutex // state is changed by ticker and by progress of engine
+	notify := func() {
+		stateMutex.Lock()
+		state := *artistState
+		stateMutex.Unlock()
+		if err := a.notifier.NotifyCreationState(ctx, state); err != nil {
+			log.Error().Err(err).Msg("[artist] failed to notify artist state")
+		}
+	}
+	artistState.PaintStep, artistState.PaintTotalSteps, artistState.PaintProgress, artistState.PaintPreview = 0, spell.Steps, 0, ""
+
 	updaterCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	go func() {
+		ticker := time.NewTicker(time.Millisecond * 1000)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-updaterCtx.Done():
 				return
-			case <-time.NewTicker(time.Millisecond * 1000).C:
+			case <-ticker.C:
+				stateMutex.Lock()
 				artistState.LastCardPaintTime = lastPaintingTime
 				artistState.CurrentCardPaintTime = uint(time.Now().Sub(paintStart).Seconds())
-				if err := a.notifier.NotifyCreationState(ctx, *artistState); err != nil {
-					log.Error().Err(err).Msg("[artist] failed to notify artist state")
-				}
+				stateMutex.Unlock()
+				notify()
 			}
 		}
 	}()
 
+	// real progress of diffusion, if engine reports it (otherwise site shows progress by previous card paint time)
+	paintCtx := engine.WithProgress(ctx, func(progress engine.Progress) {
+		if updaterCtx.Err() != nil {
+			return // late progress of finished painting
+		}
+		preview := ""
+		if progress.Preview != nil {
+			var err error
+			if preview, err = a.encodePreview(progress.Preview); err != nil {
+				log.Warn().Err(err).Msgf("[artist] failed to encode preview")
+			}
+		}
+		stateMutex.Lock()
+		artistState.PaintStep = progress.Step
+		if progress.TotalSteps > 0 {
+			artistState.PaintTotalSteps = progress.TotalSteps
+		}
+		artistState.PaintProgress = progress.Percent
+		artistState.PaintPreview = preview
+		stateMutex.Unlock()
+		notify()
+	}, a.previews)
+
 	log.Info().Msgf("[artist] start image art with spell(id=%d)", spell.ID)
-	img, err := a.engine.GetImage(ctx, spell)
+	img, info, err := a.engine.GetImage(paintCtx, spell)
 	cancel()
+	stateMutex.Lock()
+	artistState.PaintPreview = "" // preview is not needed in states after painting
+	stateMutex.Unlock()
 	if err != nil {
 		return model.Art{}, errors.Wrap(err, "[artist] failed to get image-data for art")
 	}
@@ -91,59 +136,53 @@ func (a *Artist) GetArt(
 		Spell:     spell,
 		Version:   spell.Version,
 		PaintTime: uint(paintTime.Seconds()),
+		ParentID:  artistState.ParentArtID,
+		Remix:     artistState.Remix,
+
+		ArtistVersion:    info.ArtistVersion,
+		ModelChecksum:    info.ModelChecksum,
+		WatermarkVersion: a.watermark.Version(),
 	}
 
 	art.ID = newArtID
+	art.State = model.ArtStatePainted
 
-	art, err = a.artRepo.SaveArt(ctx, art)
-	if err != nil {
-		return model.Art{}, errors.Wrap(err, "[artist] failed to save art")
-	}
-
+	// watermark contains ID of art, so images are ready before art is saved, and hash of full-size image gets into provenance
 	img, err = a.prepareImage(img, art.ID)
 	if err != nil {
 		return model.Art{}, errors.Wrap(err, "[artist] failed to prepare image")
 	}
-
-	err = a.uploadToStorage(ctx, img, art.ID)
+	fullsize, err := a.encodeFullsize(img)
 	if err != nil {
-		log.Error().Err(err).Msgf("[artist] failed to send image to storage. delete art %d", art.ID)
-		if err := a.artRepo.DeleteArt(ctx, art.ID); err != nil {
-			log.Error().Err(err).Msgf("[artist] failed to delete art after failed image creation (id=%d)", art.ID)
-		}
-		return model.Art{}, errors.Wrap(err, "[artist] failed to upload art into storage")
+		return model.Art{}, errors.Wrap(err, "[artist] failed to encode full-size image")
 	}
-
-	bts, err := a.encodeImage(img)
+	f, err := a.encodeImage(img)
 	if err != nil {
-		log.Error().Err(err).Msgf("[artist] failed to encode image. delete art %d", art.ID)
-		if err := a.artRepo.DeleteArt(ctx, art.ID); err != nil {
-			log.Error().Err(err).Msgf("[artist] failed to delete art after failed image creation (id=%d)", art.ID)
-		}
-		return model.Art{}, errors.Wrap(err, "[artist] failed to upload art into storage")
-	}
-
-	if err := a.saver.SaveArt(ctx, art.ID, bts); err != nil {
-		log.Error().Err(err).Msgf("[artist] failed to save image to saver. delete art %d", art.ID)
-		if err := a.artRepo.DeleteArt(ctx, art.ID); err != nil {
-			log.Error().Err(err).Msgf("[artist] failed to delete art after failed image creation (id=%d)", art.ID)
-		}
-		return model.Art{}, errors.Wrap(err, "[artist] failed to upload art into storage")
+		return model.Art{}, errors.Wrap(err, "[artist] failed to encode image")
 	}
+	hash := sha256.Sum256(fullsize)
+	art.ImageHash = hex.EncodeToString(hash[:])
 
-	art.UploadedToMemory = true
-	art.UploadedToStorage = true
+	// phase 1: art is painted, until images are in spool it is rolled back on failure
 	art, err = a.artRepo.SaveArt(ctx, art)
 	if err != nil {
-		// TODO need to test delete failed art without image
-		if err := a.artRepo.DeleteArt(ctx, art.ID); err != nil {
-			log.Error().Err(err).Msgf("[artist] failed to delete art after failed image creation (id=%d)", art.ID)
-		}
 		return model.Art{}, errors.Wrap(err, "[artist] failed to save art")
 	}
 
-	log.Info().Msgf("Received and saved art from artist: id=%d", art.ID)
-	return art, err
+	// phase 2: images are in local spool, art is not deleted anymore. Uploader moves art to stored and published states
+	if err := a.uploader.Store(ctx, art.ID, fullsize, f); err != nil {
+		a.rollback(ctx, art.ID)
+		return model.Art{}, errors.Wrap(err, "[artist] failed to spool images")
+	}
+	log.Info().Msgf("Received and saved art from artist: id=%d, uploader publishes it from spool", art.ID)
+	return art, nil
+}
+
+// rollback deletes painted art without images (if it fails, reconciler deletes art later)
+func (a *Artist) rollback(ctx context.Context, artID uint) {
+	if err := a.artRepo.DeleteArt(ctx, artID); err != nil {
+		log.Error().Err(err).Msgf("[artist] failed to delete art after failed image creation (id=%d)", artID)
+	}
 }
 
 // add watermark
@@ -171,17 +210,24 @@ func (a *Artist) encodeImage(img image.Image) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// upload image to storage with original size with quality 95
-func (a *Artist) uploadToStorage(ctx context.Context, img image.Image, artID uint) error {
-	log.Info().Msgf("[artist] upload art %d to storage", artID)
-	filename := fmt.Sprintf("art-%d.jpg", artID)
+// encodePreview makes small jpeg of intermediate latent for site (it goes through redis with every progress step)
+func (a *Artist) encodePreview(img image.Image) (string, error) {
+	img, err := resizer.ResizeImage(img, model.SizeXS)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resize preview")
+	}
 	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: model.QualityXS}); err != nil {
+		return "", errors.Wrap(err, "failed to encode preview")
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
 
+// encode image with original size with quality 95 (for storage)
+func (a *Artist) encodeFullsize(img image.Image) ([]byte, error) {
+	buf := new(bytes.Buffer)
 	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: model.QualityXF}); err != nil {
-		return errors.Wrapf(err, "[artist] failed to encode image into jpeg with q=%d", model.QualityXF)
+		return []byte{}, errors.Wrapf(err, "[artist] failed to encode image into jpeg with q=%d", model.QualityXF)
 	}
-	if err := a.saver.SaveFullsize(ctx, artID, buf.Bytes()); err != nil {
-		return errors.Wrapf(err, "[artist] failed to save image to storage %s", filename)
-	}
-	return nil
+	return buf.Bytes(), nil
 }
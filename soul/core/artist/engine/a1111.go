@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	A1111ArtistVersion   = "a1111"
+	A1111DefaultUpscaler = "R-ESRGAN 4x+"
+)
+
+// a1111Samplers - InvokeAI sampler names (used in dictionaries) to Automatic1111 names. Unknown names are sent as is
+var a1111Samplers = map[string]string{
+	"ddim":        "DDIM",
+	"plms":        "PLMS",
+	"k_lms":       "LMS",
+	"k_dpm_2":     "DPM2",
+	"k_dpm_2_a":   "DPM2 a",
+	"k_euler":     "Euler",
+	"k_euler_a":   "Euler a",
+	"k_heun":      "Heun",
+	"k_dpmpp_2":   "DPM++ 2M",
+	"k_dpmpp_2_a": "DPM++ 2S a",
+}
+
+type a1111Txt2ImgRequest struct {
+	Prompt           string                 `json:"prompt"`
+	NegativePrompt   string                 `json:"negative_prompt,omitempty"`
+	Seed             int64                  `json:"seed"`
+	Steps            uint                   `json:"steps"`
+	CFGScale         float64                `json:"cfg_scale,omitempty"`
+	Width            uint                   `json:"width"`
+	Height           uint                   `json:"height"`
+	SamplerName      string                 `json:"sampler_name,omitempty"`
+	BatchSize        uint                   `json:"batch_size"`
+	OverrideSettings map[string]interface{} `json:"override_settings,omitempty"`
+	SendImages       bool                   `json:"send_images"`
+	SaveImages       bool                   `json:"save_images"`
+	RestoreSettings  bool                   `json:"override_settings_restore_afterwards"`
+}
+
+type a1111Txt2ImgResponse struct {
+	Images []string `json:"images"` // base64 png
+	Info   string   `json:"info"`   // JSON of a1111Info
+}
+
+// a1111Info - parameters of painted image, as webui saves them (model of this painting, not currently loaded one)
+type a1111Info struct {
+	SDModelHash string `json:"sd_model_hash"`
+}
+
+type a1111UpscaleRequest struct {
+	Image             string `json:"image"`
+	UpscalingResize   uint   `json:"upscaling_resize"`
+	Upscaler1         string `json:"upscaler_1"`
+	ResizeMode        uint   `json:"resize_mode"` // 0 - scale by upscaling_resize
+	ShowExtrasResults bool   `json:"show_extras_results"`
+}
+
+type a1111UpscaleResponse struct {
+	Image string `json:"image"` // base64 png
+}
+
+type a1111Progress struct {
+	Progress float64 `json:"progress"` // 0..1
+	State    struct {
+		SamplingStep  uint `json:"sampling_step"`
+		SamplingSteps uint `json:"sampling_steps"`
+	} `json:"state"`
+	CurrentImage string `json:"current_image"` // base64, only without skip_current_image
+}
+
+/*
+A1111Engine - Automatic1111 stable-diffusion-webui (started with --api):
+POST /sdapi/v1/txt2img paints the spell, POST /sdapi/v1/extra-single-image upscales it (if spell.Upscale > 1).
+Checksum of model is sd_model_hash of txt2img info (after painting webui restores settings, so loaded model can be other).
+txt2img is synchronous, so progress is polled from GET /sdapi/v1/progress while it runs,
+and painting is stopped with POST /sdapi/v1/interrupt when ctx is done.
+Spell.EngineModel is a checkpoint title of webui (empty - currently loaded checkpoint).
+*/
+type A1111Engine struct {
+	url          string
+	timeout      time.Duration
+	upscaler     string
+	pollInterval time.Duration
+}
+
+func NewA1111Engine(url string, timeout time.Duration, upscaler string) *A1111Engine {
+	if upscaler == "" {
+		upscaler = A1111DefaultUpscaler
+	}
+	return &A1111Engine{strings.TrimRight(url, "/"), timeout, upscaler, JobPollInterval}
+}
+
+func (e *A1111Engine) Ping(ctx context.Context) error {
+	return ping(ctx, e.url+"/sdapi/v1/options")
+}
+
+func (e *A1111Engine) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	request := a1111Txt2ImgRequest{
+		Prompt:          spell.Tags,
+		NegativePrompt:  spell.NegativePrompt,
+		Seed:            int64(spell.Seed),
+		Steps:           spell.Steps,
+		CFGScale:        spell.CFGScale,
+		Width:           spell.Width,
+		Height:          spell.Height,
+		SamplerName:     samplerName(a1111Samplers, spell.Sampler),
+		BatchSize:       1,
+		SendImages:      true,
+		RestoreSettings: true,
+	}
+	if spell.EngineModel != "" {
+		request.OverrideSettings = map[string]interface{}{"sd_model_checkpoint": spell.EngineModel}
+	}
+	var response a1111Txt2ImgResponse
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	go e.watchProgress(watchCtx)
+	err := postJSON(ctx, e.url+"/sdapi/v1/txt2img", request, &response)
+	stopWatch()
+	if err != nil {
+		if ctx.Err() != nil {
+			e.interrupt()
+		}
+		return nil, model.EngineInfo{}, errors.Wrap(err, "[a1111] failed to paint")
+	}
+	if len(response.Images) == 0 {
+		return nil, model.EngineInfo{}, errors.New("[a1111] no images in txt2img response")
+	}
+	encoded := response.Images[0]
+
+	// checksum is not critical for painting, card is saved without it
+	info := model.EngineInfo{ArtistVersion: A1111ArtistVersion}
+	var paintingInfo a1111Info
+	if err := json.Unmarshal([]byte(response.Info), &paintingInfo); err != nil {
+		log.Warn().Err(err).Msgf("[a1111] failed to parse info of painting")
+	}
+	info.ModelChecksum = paintingInfo.SDModelHash
+
+	if spell.Upscale > 1 {
+		var upscaled a1111UpscaleResponse
+		err := postJSON(ctx, e.url+"/sdapi/v1/extra-single-image", a1111UpscaleRequest{
+			Image:           encoded,
+			UpscalingResize: spell.Upscale,
+			Upscaler1:       e.upscaler,
+		}, &upscaled)
+		if err != nil {
+			return nil, model.EngineInfo{}, errors.Wrap(err, "[a1111] failed to upscale")
+		}
+		encoded = upscaled.Image
+	}
+	img, err := decodeBase64Image(encoded)
+	if err != nil {
+		return nil, model.EngineInfo{}, errors.Wrap(err, "[a1111] failed to decode image")
+	}
+	return img, info, nil
+}
+
+// watchProgress reports progress of current painting until ctx is done (webui paints one image at a time)
+func (e *A1111Engine) watchProgress(ctx context.Context) {
+	if ctx.Value(progressKey{}) == nil {
+		return // nobody listens
+	}
+	progressURL := e.url + "/sdapi/v1/progress?skip_current_image=" + strconv.FormatBool(!wantPreviews(ctx))
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	var lastStep uint
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var status a1111Progress
+		if err := getJSON(ctx, progressURL, &status); err != nil {
+			continue // progress is not critical
+		}
+		if status.State.SamplingStep == lastStep && status.CurrentImage == "" {
+			continue
+		}
+		lastStep = status.State.SamplingStep
+		progress := Progress{
+			Step:       status.State.SamplingStep,
+			TotalSteps: status.State.SamplingSteps,
+			Percent:    status.Progress * 100,
+		}
+		if status.CurrentImage != "" {
+			progress.Preview, _ = decodeBase64Image(status.CurrentImage)
+		}
+		reportProgress(ctx, progress)
+	}
+}
+
+// interrupt stops painting of webui, nobody waits for it
+func (e *A1111Engine) interrupt() {
+	ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+	defer cancel()
+	if err := postJSON(ctx, e.url+"/sdapi/v1/interrupt", struct{}{}, &struct{}{}); err != nil {
+		log.Error().Err(err).Msgf("[a1111] failed to interrupt painting")
+	}
+}
+
+// samplerName translates sampler of dictionary to engine's name
+func samplerName(names map[string]string, sampler string) string {
+	if name, found := names[sampler]; found {
+		return name
+	}
+	return sampler
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testSpell() model.Spell {
+	return model.Spell{
+		Tags:           "cat,moon",
+		NegativePrompt: "blurry",
+		Seed:           42,
+		Sampler:        "k_euler_a",
+		Steps:          30,
+		CFGScale:       7.5,
+		Width:          8,
+		Height:         12,
+		Upscale:        2,
+		EngineModel:    "sd-1.5",
+	}
+}
+
+func TestA1111Engine(t *testing.T) {
+	var txt2img map[string]interface{}
+	var upscale map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sdapi/v1/txt2img", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&txt2img)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"images": []string{base64.StdEncoding.EncodeToString(testPNG(t, 8, 12))},
+			"info":   `{"sd_model_name": "sd-1.5", "sd_model_hash": "abc123"}`,
+		})
+	})
+	mux.HandleFunc("/sdapi/v1/extra-single-image", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&upscale)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"image": base64.StdEncoding.EncodeToString(testPNG(t, 16, 24)),
+		})
+	})
+	mux.HandleFunc("/sdapi/v1/options", func(w http.ResponseWriter, r *http.Request) {
+		// settings are restored after painting, loaded model is not the model of painting
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"sd_model_checkpoint": "sd-2.1", "sd_checkpoint_hash": "def456"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	e := NewA1111Engine(server.URL, time.Second*5, "")
+	if err := e.Ping(context.Background()); err != nil {
+		t.Fatalf("ping failed: %s", err)
+	}
+	img, info, err := e.GetImage(context.Background(), testSpell())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if img.Bounds().Dx() != 16 || img.Bounds().Dy() != 24 {
+		t.Fatalf("expected upscaled image, got %s", img.Bounds())
+	}
+	if info.ArtistVersion != A1111ArtistVersion || info.ModelChecksum != "abc123" {
+		t.Fatalf("unexpected engine info %+v", info)
+	}
+	if txt2img["prompt"] != "cat,moon" || txt2img["negative_prompt"] != "blurry" || txt2img["seed"] != 42.0 ||
+		txt2img["steps"] != 30.0 || txt2img["cfg_scale"] != 7.5 || txt2img["width"] != 8.0 || txt2img["height"] != 12.0 ||
+		txt2img["sampler_name"] != "Euler a" {
+		t.Fatalf("unexpected txt2img request %+v", txt2img)
+	}
+	if settings, _ := txt2img["override_settings"].(map[string]interface{}); settings["sd_model_checkpoint"] != "sd-1.5" {
+		t.Fatalf("model is not sent: %+v", txt2img)
+	}
+	if upscale["upscaling_resize"] != 2.0 || upscale["upscaler_1"] != A1111DefaultUpscaler {
+		t.Fatalf("unexpected upscale request %+v", upscale)
+	}
+}
+
+func TestA1111EngineError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "CUDA out of memory", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	e := NewA1111Engine(server.URL, time.Second*5, "")
+	if _, _, err := e.GetImage(context.Background(), testSpell()); err == nil {
+		t.Fatalf("expected error")
+	}
+	if err := e.Ping(context.Background()); err == nil {
+		t.Fatalf("expected ping error")
+	}
+}
+
+func TestComfyUIEngine(t *testing.T) {
+	var prompt comfyUIPromptRequest
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prompt", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&prompt)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"prompt_id": "p1", "number": 1})
+	})
+	mux.HandleFunc("/history/p1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			_, _ = w.Write([]byte("{}")) // queued
+			return
+		}
+		_, _ = w.Write([]byte(`{"p1": {"outputs": {"9": {"images": [{"filename": "a.png", "subfolder": "", "type": "output"}]}},
+			"status": {"status_str": "success", "completed": true}}}`))
+	})
+	mux.HandleFunc("/view", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filename") != "a.png" || r.URL.Query().Get("type") != "output" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(testPNG(t, 16, 24))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	e := NewComfyUIEngine(server.URL, time.Second*5, "default.safetensors", "", map[string]string{"sd-1.5": "abc123"})
+	e.pollInterval = time.Millisecond
+	img, info, err := e.GetImage(context.Background(), testSpell())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if img.Bounds().Dx() != 16 || info.ArtistVersion != ComfyUIArtistVersion || info.ModelChecksum != "abc123" || polls != 3 {
+		t.Fatalf("unexpected result %s, %+v, polls=%d", img.Bounds(), info, polls)
+	}
+
+	graph := prompt.Prompt
+	if graph["4"].Inputs["ckpt_name"] != "sd-1.5" || graph["6"].Inputs["text"] != "cat,moon" || graph["7"].Inputs["text"] != "blurry" {
+		t.Fatalf("unexpected graph %+v", graph)
+	}
+	sampler := graph["3"].Inputs
+	if sampler["seed"] != 42.0 || sampler["steps"] != 30.0 || sampler["cfg"] != 7.5 || sampler["sampler_name"] != "euler_ancestral" {
+		t.Fatalf("unexpected sampler %+v", sampler)
+	}
+	if graph["11"].ClassType != "ImageScaleBy" || graph["11"].Inputs["scale_by"] != 2.0 {
+		t.Fatalf("expected lanczos upscale, got %+v", graph["11"])
+	}
+}
+
+func TestComfyUIEngineFailedPrompt(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/prompt", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"prompt_id": "p1"}`))
+	})
+	mux.HandleFunc("/history/p1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"p1": {"outputs": {}, "status": {"status_str": "error", "completed": false}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	e := NewComfyUIEngine(server.URL, time.Second*5, "", "up.pth", nil)
+	e.pollInterval = time.Millisecond
+	spell := testSpell()
+	if _, _, err := e.GetImage(context.Background(), spell); err == nil {
+		t.Fatalf("expected error of failed prompt")
+	}
+	spell.EngineModel = ""
+	if _, _, err := e.GetImage(context.Background(), spell); err == nil {
+		t.Fatalf("expected error without checkpoint")
+	}
+}
@@ -11,41 +11,195 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// headers of artist response with provenance of painting (empty if artist is old)
+const (
+	HeaderArtistVersion = "X-Artist-Version"
+	HeaderModelChecksum = "X-Model-Checksum"
+
+	DefaultTimeout = time.Second * 90 // painting with upscale
+	PingTimeout    = time.Second * 5
+)
+
+/*
+ArtistEngine - our artist service (InvokeAI wrapper). Old artist paints synchronously: POST /painting returns png.
+Async artist (async=true) has job protocol with real progress (see RunJob):
+
+	POST   /jobs             - same form as /painting (+ preview=true), returns JobStatus with id
+	GET    /jobs/<id>        - JobStatus (?preview=true - with intermediate latent)
+	GET    /jobs/<id>/result - png with X-Artist-Version and X-Model-Checksum headers
+	DELETE /jobs/<id>        - cancel job
+*/
 type ArtistEngine struct {
-	artistURL string
+	artistURL    string
+	timeout      time.Duration
+	async        bool
+	pollInterval time.Duration
 }
 
-func NewArtistEngine(artistURL string) *ArtistEngine {
-	return &ArtistEngine{artistURL}
+func NewArtistEngine(artistURL string, timeout time.Duration, async bool) *ArtistEngine {
+	return &ArtistEngine{artistURL, timeout, async, JobPollInterval}
 }
 
-func (e *ArtistEngine) GetImage(ctx context.Context, spell model.Spell) (image.Image, error) {
+// Ping checks that artist answers (any answer except 5xx)
+func (e *ArtistEngine) Ping(ctx context.Context) error {
+	return ping(ctx, e.artistURL+"/")
+}
+
+func (e *ArtistEngine) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	if e.async {
+		ctx, cancel := context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+		return RunJob(ctx, e, spell, e.pollInterval)
+	}
+
 	client := http.Client{
-		Timeout: time.Second * 90,
+		Timeout: e.timeout,
 	}
-	response, err := client.PostForm(e.artistURL+"/painting", url.Values{
+	request, err := e.paintingRequest(ctx, e.artistURL+"/painting", spell)
+	if err != nil {
+		return nil, model.EngineInfo{}, err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, model.EngineInfo{}, errors.Wrap(err, "failed to make request to artist")
+	}
+	defer response.Body.Close()
+	return readPainting(response)
+}
+
+func (e *ArtistEngine) Submit(ctx context.Context, spell model.Spell) (string, error) {
+	request, err := e.paintingRequest(ctx, e.artistURL+"/jobs", spell)
+	if err != nil {
+		return "", err
+	}
+	var status JobStatus
+	if err := doJSON(request, &status); err != nil {
+		return "", errors.Wrap(err, "[artist] failed to submit job")
+	}
+	if status.ID == "" {
+		return "", errors.New("[artist] empty job id")
+	}
+	return status.ID, nil
+}
+
+func (e *ArtistEngine) Status(ctx context.Context, jobID string) (JobStatus, error) {
+	jobURL := e.jobURL(jobID)
+	if wantPreviews(ctx) {
+		jobURL += "?preview=true"
+	}
+	var status JobStatus
+	err := getJSON(ctx, jobURL, &status)
+	return status, errors.Wrap(err, "[artist] failed to get job status")
+}
+
+func (e *ArtistEngine) Result(ctx context.Context, jobID string) (image.Image, model.EngineInfo, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.jobURL(jobID)+"/result", nil)
+	if err != nil {
+		return nil, model.EngineInfo{}, errors.Wrap(err, "[artist] failed to make request")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, model.EngineInfo{}, errors.Wrap(err, "failed to make request to artist")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, model.EngineInfo{}, errors.Errorf("[artist] result of job %s: %s", jobID, response.Status)
+	}
+	return readPainting(response)
+}
+
+func (e *ArtistEngine) Cancel(ctx context.Context, jobID string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, e.jobURL(jobID), nil)
+	if err != nil {
+		return errors.Wrap(err, "[artist] failed to make request")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "[artist] failed to cancel job")
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= http.StatusBadRequest && response.StatusCode != http.StatusNotFound {
+		return errors.Errorf("[artist] cancel of job %s: %s", jobID, response.Status)
+	}
+	return nil
+}
+
+func (e *ArtistEngine) jobURL(jobID string) string {
+	return e.artistURL + "/jobs/" + url.PathEscape(jobID)
+}
+
+func (e *ArtistEngine) paintingRequest(ctx context.Context, requestURL string, spell model.Spell) (*http.Request, error) {
+	values := url.Values{
 		"tags":    {spell.Tags},
 		"seed":    {fmt.Sprintf("%d", spell.Seed)},
-		"width":   {"640"},
-		"height":  {"960"},
-		"steps":   {"50"},
-		"upscale": {"4"},
+		"width":   {fmt.Sprintf("%d", spell.Width)},
+		"height":  {fmt.Sprintf("%d", spell.Height)},
+		"steps":   {fmt.Sprintf("%d", spell.Steps)},
+		"upscale": {fmt.Sprintf("%d", spell.Upscale)},
 		"version": {spell.Version},
-	})
+	}
+	// optional params, artist uses its defaults without them
+	if spell.NegativePrompt != "" {
+		values.Set("negative_prompt", spell.NegativePrompt)
+	}
+	if spell.EngineModel != "" {
+		values.Set("model", spell.EngineModel)
+	}
+	if spell.Sampler != "" {
+		values.Set("sampler", spell.Sampler)
+	}
+	if spell.CFGScale != 0 {
+		values.Set("cfg_scale", strconv.FormatFloat(spell.CFGScale, 'f', -1, 64))
+	}
+	if e.async && wantPreviews(ctx) {
+		values.Set("preview", "true")
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, strings.NewReader(values.Encode()))
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to make request to artist")
+		return nil, errors.Wrap(err, "[artist] failed to make request")
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request, nil
+}
+
+// readPainting reads png and provenance headers of artist response
+func readPainting(response *http.Response) (image.Image, model.EngineInfo, error) {
+	info := model.EngineInfo{
+		ArtistVersion: response.Header.Get(HeaderArtistVersion),
+		ModelChecksum: response.Header.Get(HeaderModelChecksum),
 	}
-	defer response.Body.Close()
 
 	bts, err := io.ReadAll(response.Body)
-	r := bytes.NewReader(bts)
-	img, err := png.Decode(r)
 	if err != nil {
-		return nil, errors.Wrap(err, "[artist] failed to get valid jpeg")
+		return nil, info, errors.Wrap(err, "[artist] failed to read response body")
+	}
+	img, err := png.Decode(bytes.NewReader(bts))
+	if err != nil {
+		return nil, info, errors.Wrap(err, "[artist] failed to get valid png")
 	}
+	return img, info, nil
+}
 
-	return img, errors.Wrap(err, "[artist] failed to read response body")
+func ping(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "[engine] failed to make ping request %s", url)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "[engine] %s is not available", url)
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("[engine] %s answered %s", url, response.Status)
+	}
+	return nil
 }
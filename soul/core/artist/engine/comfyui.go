@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ComfyUIArtistVersion    = "comfyui"
+	ComfyUIPollInterval     = time.Second
+	ComfyUIClientID         = "artchitect"
+	ComfyUIDefaultSampler   = "euler"
+	ComfyUIDefaultCFGScale  = 7.0
+	comfyUIOutputNode       = "9"
+	comfyUIStatusError      = "error"
+	comfyUIDefaultScheduler = "normal"
+)
+
+// comfyUISamplers - InvokeAI sampler names (used in dictionaries) to ComfyUI names. Unknown names are sent as is
+var comfyUISamplers = map[string]string{
+	"ddim":        "ddim",
+	"k_lms":       "lms",
+	"k_dpm_2":     "dpm_2",
+	"k_dpm_2_a":   "dpm_2_ancestral",
+	"k_euler":     "euler",
+	"k_euler_a":   "euler_ancestral",
+	"k_heun":      "heun",
+	"k_dpmpp_2":   "dpmpp_2m",
+	"k_dpmpp_2_a": "dpmpp_2s_ancestral",
+}
+
+type comfyUINode struct {
+	ClassType string                 `json:"class_type"`
+	Inputs    map[string]interface{} `json:"inputs"`
+}
+
+type comfyUIPromptRequest struct {
+	Prompt   map[string]comfyUINode `json:"prompt"`
+	ClientID string                 `json:"client_id"`
+}
+
+type comfyUIPromptResponse struct {
+	PromptID string `json:"prompt_id"`
+}
+
+type comfyUIImage struct {
+	Filename  string `json:"filename"`
+	Subfolder string `json:"subfolder"`
+	Type      string `json:"type"`
+}
+
+type comfyUIHistory struct {
+	Outputs map[string]struct {
+		Images []comfyUIImage `json:"images"`
+	} `json:"outputs"`
+	Status struct {
+		StatusStr string `json:"status_str"`
+		Completed bool   `json:"completed"`
+	} `json:"status"`
+}
+
+/*
+ComfyUIEngine - ComfyUI server. Spell is turned into prompt graph (checkpoint -> sampler -> decode -> upscale -> save),
+graph is queued with POST /prompt, then GET /history/<prompt_id> is polled until prompt is done,
+and image is downloaded with GET /view. Unfinished prompt is cancelled when ctx is done.
+ComfyUI reports progress of steps only in websocket, so there is no step progress from this engine.
+Spell.EngineModel is a checkpoint file name (empty - checkpoint of backend config).
+ModelChecksum is taken from checksums of backend config (ComfyUI does not hash checkpoints), unknown checkpoint has no checksum.
+Upscale is made with upscale model if it is set in config (x4 models give x4 regardless of spell), otherwise with lanczos.
+*/
+type ComfyUIEngine struct {
+	url          string
+	timeout      time.Duration
+	defaultModel string
+	upscaleModel string
+	checksums    map[string]string // checkpoint file -> sha256
+	pollInterval time.Duration
+}
+
+func NewComfyUIEngine(url string, timeout time.Duration, checkpoint string, upscaleModel string, checksums map[string]string) *ComfyUIEngine {
+	return &ComfyUIEngine{strings.TrimRight(url, "/"), timeout, checkpoint, upscaleModel, checksums, ComfyUIPollInterval}
+}
+
+func (e *ComfyUIEngine) Ping(ctx context.Context) error {
+	return ping(ctx, e.url+"/system_stats")
+}
+
+func (e *ComfyUIEngine) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	info := model.EngineInfo{ArtistVersion: ComfyUIArtistVersion}
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	graph, err := e.graph(spell)
+	if err != nil {
+		return nil, info, err
+	}
+	info.ModelChecksum = e.checksums[e.checkpoint(spell)]
+	var queued comfyUIPromptResponse
+	if err := postJSON(ctx, e.url+"/prompt", comfyUIPromptRequest{graph, ComfyUIClientID}, &queued); err != nil {
+		return nil, info, errors.Wrap(err, "[comfyui] failed to queue prompt")
+	}
+	if queued.PromptID == "" {
+		return nil, info, errors.New("[comfyui] empty prompt_id")
+	}
+
+	result, err := e.wait(ctx, queued.PromptID)
+	if err != nil {
+		return nil, info, err
+	}
+	img, err := e.download(ctx, result)
+	if err != nil {
+		return nil, info, errors.Wrapf(err, "[comfyui] failed to download image of prompt %s", queued.PromptID)
+	}
+	return img, info, nil
+}
+
+// graph - ComfyUI API-format prompt (node id -> node), links are [node id, output index]
+func (e *ComfyUIEngine) graph(spell model.Spell) (map[string]comfyUINode, error) {
+	checkpoint := e.checkpoint(spell)
+	if checkpoint == "" {
+		return nil, errors.New("[comfyui] no checkpoint in spell and in backend config")
+	}
+	sampler := samplerName(comfyUISamplers, spell.Sampler)
+	if sampler == "" {
+		sampler = ComfyUIDefaultSampler
+	}
+	cfg := spell.CFGScale
+	if cfg == 0 {
+		cfg = ComfyUIDefaultCFGScale
+	}
+
+	graph := map[string]comfyUINode{
+		"4": {"CheckpointLoaderSimple", map[string]interface{}{"ckpt_name": checkpoint}},
+		"5": {"EmptyLatentImage", map[string]interface{}{"width": spell.Width, "height": spell.Height, "batch_size": 1}},
+		"6": {"CLIPTextEncode", map[string]interface{}{"text": spell.Tags, "clip": []interface{}{"4", 1}}},
+		"7": {"CLIPTextEncode", map[string]interface{}{"text": spell.NegativePrompt, "clip": []interface{}{"4", 1}}},
+		"3": {"KSampler", map[string]interface{}{
+			"seed":         spell.Seed,
+			"steps":        spell.Steps,
+			"cfg":          cfg,
+			"sampler_name": sampler,
+			"scheduler":    comfyUIDefaultScheduler,
+			"denoise":      1.0,
+			"model":        []interface{}{"4", 0},
+			"positive":     []interface{}{"6", 0},
+			"negative":     []interface{}{"7", 0},
+			"latent_image": []interface{}{"5", 0},
+		}},
+		"8": {"VAEDecode", map[string]interface{}{"samples": []interface{}{"3", 0}, "vae": []interface{}{"4", 2}}},
+	}
+	output := []interface{}{"8", 0}
+	if spell.Upscale > 1 {
+		if e.upscaleModel != "" {
+			graph["10"] = comfyUINode{"UpscaleModelLoader", map[string]interface{}{"model_name": e.upscaleModel}}
+			graph["11"] = comfyUINode{"ImageUpscaleWithModel", map[string]interface{}{"upscale_model": []interface{}{"10", 0}, "image": output}}
+		} else {
+			graph["11"] = comfyUINode{"ImageScaleBy", map[string]interface{}{"upscale_method": "lanczos", "scale_by": spell.Upscale, "image": output}}
+		}
+		output = []interface{}{"11", 0}
+	}
+	graph[comfyUIOutputNode] = comfyUINode{"SaveImage", map[string]interface{}{"filename_prefix": "artchitect", "images": output}}
+	return graph, nil
+}
+
+// checkpoint - checkpoint file of spell (empty - no checkpoint in spell and in config)
+func (e *ComfyUIEngine) checkpoint(spell model.Spell) string {
+	if spell.EngineModel != "" {
+		return spell.EngineModel
+	}
+	return e.defaultModel
+}
+
+// wait polls history of prompt until it is finished (prompt is absent in history while it is queued or running)
+func (e *ComfyUIEngine) wait(ctx context.Context, promptID string) (comfyUIImage, error) {
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.cancel(promptID)
+			return comfyUIImage{}, errors.Wrapf(ctx.Err(), "[comfyui] prompt %s is not finished", promptID)
+		case <-ticker.C:
+		}
+		history := make(map[string]comfyUIHistory)
+		if err := getJSON(ctx, e.url+"/history/"+url.PathEscape(promptID), &history); err != nil {
+			if ctx.Err() != nil {
+				e.cancel(promptID)
+			}
+			return comfyUIImage{}, errors.Wrapf(err, "[comfyui] failed to get history of prompt %s", promptID)
+		}
+		entry, found := history[promptID]
+		if !found {
+			continue
+		}
+		if entry.Status.StatusStr == comfyUIStatusError {
+			return comfyUIImage{}, errors.Errorf("[comfyui] prompt %s failed", promptID)
+		}
+		if !entry.Status.Completed {
+			continue
+		}
+		images := entry.Outputs[comfyUIOutputNode].Images
+		if len(images) == 0 {
+			return comfyUIImage{}, errors.Errorf("[comfyui] no images in output of prompt %s", promptID)
+		}
+		return images[0], nil
+	}
+}
+
+// cancel removes prompt from queue and interrupts it if it is running (interrupt stops current prompt, whatever it is)
+func (e *ComfyUIEngine) cancel(promptID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+	defer cancel()
+	if err := postJSON(ctx, e.url+"/queue", map[string]interface{}{"delete": []string{promptID}}, &struct{}{}); err != nil {
+		log.Error().Err(err).Msgf("[comfyui] failed to delete prompt %s from queue", promptID)
+	}
+	if err := postJSON(ctx, e.url+"/interrupt", struct{}{}, &struct{}{}); err != nil {
+		log.Error().Err(err).Msgf("[comfyui] failed to interrupt prompt %s", promptID)
+	}
+}
+
+func (e *ComfyUIEngine) download(ctx context.Context, img comfyUIImage) (image.Image, error) {
+	query := url.Values{"filename": {img.Filename}, "subfolder": {img.Subfolder}, "type": {img.Type}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+"/view?"+query.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make request")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make request")
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("/view answered %s", response.Status)
+	}
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read image")
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	return decoded, errors.Wrap(err, "failed to decode image")
+}
@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+const (
+	BackendTypeArtist  = "artist"  // our artist service (ArtistEngine)
+	BackendTypeFake    = "fake"    // pictures from files/fakes
+	BackendTypeA1111   = "a1111"   // Automatic1111 stable-diffusion-webui API (A1111Engine)
+	BackendTypeComfyUI = "comfyui" // ComfyUI prompt API (ComfyUIEngine)
+)
+
+/*
+RouterConfig - backends of artist router (file from env ARTIST_BACKENDS):
+
+	health_interval: 30s
+	backends:
+	  - name: home-gpu
+	    type: artist
+	    url: http://192.168.1.10:8083
+	    weight: 3
+	    timeout: 90s
+	    async: true      # artist with jobs and progress (see ArtistEngine)
+	    models:          # model of version manifest -> model of backend (without models it is sent as is)
+	      sd-1.5: stable-diffusion-1.5                # InvokeAI models.yaml name
+	  - name: rented-gpu
+	    type: artist
+	    url: http://10.0.0.2:8083
+	    weight: 0        # reserve, used only when others failed
+	  - name: webui
+	    type: a1111
+	    url: http://10.0.0.3:7860
+	    upscaler: R-ESRGAN 4x+       # upscaler of extras tab (default R-ESRGAN 4x+)
+	    models:
+	      sd-1.5: v1-5-pruned-emaonly.safetensors [6ce0161689]   # checkpoint title
+	  - name: comfy
+	    type: comfyui
+	    url: http://10.0.0.4:8188
+	    checkpoint: v1-5-pruned-emaonly.safetensors   # used when spell has no model (required for comfyui)
+	    upscaler: RealESRGAN_x4plus.pth               # upscale model (empty - lanczos)
+	    models:
+	      sd-1.5: v1-5-pruned-emaonly.safetensors     # checkpoint file
+	    checksums:       # checkpoint file -> sha256 (ComfyUI API does not tell checksum of model)
+	      v1-5-pruned-emaonly.safetensors: cc6cb27103417325ff94f52b7a5d2dde45a7515b25c255d8e396c90014281516
+
+Backend with models paints only spells of these models (and spells without model), others go to other backends.
+*/
+type RouterConfig struct {
+	HealthInterval time.Duration   `yaml:"health_interval"`
+	Backends       []BackendConfig `yaml:"backends"`
+}
+
+type BackendConfig struct {
+	Name    string        `yaml:"name"`
+	Type    string        `yaml:"type"`
+	URL     string        `yaml:"url"`
+	Weight  *uint         `yaml:"weight"` // default 1
+	Timeout time.Duration `yaml:"timeout"`
+
+	Models     map[string]string `yaml:"models"`     // Spell.EngineModel -> model of backend (nil - any model, sent as is)
+	Async      bool              `yaml:"async"`      // artist with job protocol
+	Checkpoint string            `yaml:"checkpoint"` // comfyui
+	Upscaler   string            `yaml:"upscaler"`   // a1111, comfyui
+	Checksums  map[string]string `yaml:"checksums"`  // comfyui
+}
+
+func LoadRouterConfig(filename string) (RouterConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return RouterConfig{}, errors.Wrapf(err, "[router] failed to read %s", filename)
+	}
+	var config RouterConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return RouterConfig{}, errors.Wrapf(err, "[router] failed to parse %s", filename)
+	}
+	if config.HealthInterval == 0 {
+		config.HealthInterval = DefaultHealthInterval
+	}
+	if len(config.Backends) == 0 {
+		return RouterConfig{}, errors.Errorf("[router] no backends in %s", filename)
+	}
+	names := make(map[string]bool)
+	for idx, backend := range config.Backends {
+		if backend.Name == "" {
+			return RouterConfig{}, errors.Errorf("[router] backend %d has no name", idx)
+		}
+		if names[backend.Name] {
+			return RouterConfig{}, errors.Errorf("[router] duplicate backend %s", backend.Name)
+		}
+		names[backend.Name] = true
+		if backend.Type != BackendTypeFake && backend.URL == "" {
+			return RouterConfig{}, errors.Errorf("[router] backend %s has no url", backend.Name)
+		}
+	}
+	return config, nil
+}
+
+// NewRouterFromConfig makes router with backends of config (fakeGenerationTime is for fake backends)
+func NewRouterFromConfig(config RouterConfig, fakeGenerationTime uint) (*Router, error) {
+	router := NewRouter(config.HealthInterval)
+	for _, backend := range config.Backends {
+		timeout := backend.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		weight := uint(1)
+		if backend.Weight != nil {
+			weight = *backend.Weight
+		}
+
+		var engine Backend
+		switch backend.Type {
+		case BackendTypeArtist:
+			engine = NewArtistEngine(backend.URL, timeout, backend.Async)
+		case BackendTypeFake:
+			engine = NewFakeEngine(fakeGenerationTime)
+		case BackendTypeA1111:
+			engine = NewA1111Engine(backend.URL, timeout, backend.Upscaler)
+		case BackendTypeComfyUI:
+			engine = NewComfyUIEngine(backend.URL, timeout, backend.Checkpoint, backend.Upscaler, backend.Checksums)
+		default:
+			return nil, errors.Errorf("[router] backend %s has unknown type %s", backend.Name, backend.Type)
+		}
+		router.AddModelBackend(backend.Name, weight, engine, backend.Models)
+	}
+	return router, nil
+}
@@ -23,14 +23,19 @@ func NewFakeEngine(fakeGenerationTime uint) *FakeEngine {
 	}
 }
 
-func (e *FakeEngine) GetImage(ctx context.Context, spell model.Spell) (image.Image, error) {
+func (e *FakeEngine) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (e *FakeEngine) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	info := model.EngineInfo{ArtistVersion: "fake"}
 	fakeNumber := rand.Intn(20) + 1
 	if b, err := os.ReadFile(fmt.Sprintf("files/fakes/%d.jpeg", fakeNumber)); err != nil {
-		return nil, errors.Wrap(err, "[fake artist] failed to get file")
+		return nil, info, errors.Wrap(err, "[fake artist] failed to get file")
 	} else {
 		time.Sleep(time.Second * time.Duration(e.fakeGenerationTime)) // imitation of long-running process
 		buf := bytes.NewBuffer(b)
 		img, err := jpeg.Decode(buf)
-		return img, errors.Wrap(err, "[fake_artist] failed to decode jpeg")
+		return img, info, errors.Wrap(err, "[fake_artist] failed to decode jpeg")
 	}
 }
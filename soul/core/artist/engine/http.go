@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// helpers of JSON-speaking engines (A1111Engine, ComfyUIEngine)
+
+func decodeBase64Image(encoded string) (image.Image, error) {
+	if idx := strings.Index(encoded, ","); strings.HasPrefix(encoded, "data:") && idx >= 0 {
+		encoded = encoded[idx+1:] // data:image/png;base64,...
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+func postJSON(ctx context.Context, url string, body interface{}, result interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to marshal request to %s", url)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrapf(err, "failed to make request to %s", url)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return doJSON(request, result)
+}
+
+func getJSON(ctx context.Context, url string, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make request to %s", url)
+	}
+	return doJSON(request, result)
+}
+
+func doJSON(request *http.Request, result interface{}) error {
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return errors.Wrapf(err, "failed to make request to %s", request.URL)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read response of %s", request.URL)
+	}
+	if response.StatusCode != http.StatusOK {
+		if len(data) > 200 {
+			data = data[:200]
+		}
+		return errors.Errorf("%s answered %s: %s", request.URL, response.Status, data)
+	}
+	if len(data) == 0 {
+		return nil // commands like interrupt answer nothing
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return errors.Wrapf(err, "failed to parse response of %s", request.URL)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"time"
+)
+
+const JobPollInterval = time.Millisecond * 500
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusDone      = "done"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// Progress - real progress of painting, reported by engine
+type Progress struct {
+	Step       uint
+	TotalSteps uint
+	Percent    float64     // 0..100
+	Preview    image.Image // intermediate latent (nil - engine has no preview or previews are not requested)
+}
+
+type ProgressFunc func(progress Progress)
+
+type progressKey struct{}
+
+type progressListener struct {
+	fn       ProgressFunc
+	previews bool
+}
+
+// WithProgress - engines report progress of painting into fn (previews - ask engine for intermediate latents)
+func WithProgress(ctx context.Context, fn ProgressFunc, previews bool) context.Context {
+	return context.WithValue(ctx, progressKey{}, progressListener{fn, previews})
+}
+
+func reportProgress(ctx context.Context, progress Progress) {
+	if listener, ok := ctx.Value(progressKey{}).(progressListener); ok {
+		listener.fn(progress)
+	}
+}
+
+func wantPreviews(ctx context.Context) bool {
+	listener, ok := ctx.Value(progressKey{}).(progressListener)
+	return ok && listener.previews
+}
+
+// JobStatus - state of painting job on engine
+type JobStatus struct {
+	ID         string  `json:"id"`
+	Status     string  `json:"status"`
+	Step       uint    `json:"step"`
+	TotalSteps uint    `json:"total_steps"`
+	Percent    float64 `json:"percent"`
+	Preview    string  `json:"preview"` // base64 image, only if preview was requested
+	Error      string  `json:"error"`
+}
+
+// JobEngine - engine with asynchronous painting: spell is submitted as job, job is polled and result is fetched
+type JobEngine interface {
+	Submit(ctx context.Context, spell model.Spell) (string, error)
+	Status(ctx context.Context, jobID string) (JobStatus, error)
+	Result(ctx context.Context, jobID string) (image.Image, model.EngineInfo, error)
+	Cancel(ctx context.Context, jobID string) error
+}
+
+/*
+RunJob submits spell to engine and polls job until it is finished, progress is reported to listener of ctx.
+If ctx is done (timeout, soul is stopping), job is cancelled on engine, so GPU is not busy with nobody's painting.
+*/
+func RunJob(ctx context.Context, engine JobEngine, spell model.Spell, pollInterval time.Duration) (image.Image, model.EngineInfo, error) {
+	jobID, err := engine.Submit(ctx, spell)
+	if err != nil {
+		return nil, model.EngineInfo{}, errors.Wrap(err, "[job] failed to submit job")
+	}
+	log.Info().Msgf("[job] spell %d is submitted as job %s", spell.ID, jobID)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	var lastStep uint
+	for {
+		select {
+		case <-ctx.Done():
+			cancelJob(engine, jobID)
+			return nil, model.EngineInfo{}, errors.Wrapf(ctx.Err(), "[job] job %s is not finished", jobID)
+		case <-ticker.C:
+		}
+
+		status, err := engine.Status(ctx, jobID)
+		if err != nil {
+			cancelJob(engine, jobID) // engine may be alive, but we don't wait anymore
+			return nil, model.EngineInfo{}, errors.Wrapf(err, "[job] failed to get status of job %s", jobID)
+		}
+		switch status.Status {
+		case JobStatusQueued:
+			continue
+		case JobStatusRunning:
+			if status.Step == lastStep && status.Preview == "" {
+				continue
+			}
+			lastStep = status.Step
+			progress := Progress{Step: status.Step, TotalSteps: status.TotalSteps, Percent: status.Percent}
+			if status.Preview != "" {
+				if progress.Preview, err = decodeBase64Image(status.Preview); err != nil {
+					log.Warn().Err(err).Msgf("[job] broken preview of job %s", jobID)
+				}
+			}
+			reportProgress(ctx, progress)
+		case JobStatusDone:
+			reportProgress(ctx, Progress{Step: status.TotalSteps, TotalSteps: status.TotalSteps, Percent: 100})
+			return engine.Result(ctx, jobID)
+		case JobStatusFailed, JobStatusCancelled:
+			return nil, model.EngineInfo{}, errors.Errorf("[job] job %s is %s: %s", jobID, status.Status, status.Error)
+		default:
+			return nil, model.EngineInfo{}, errors.Errorf("[job] job %s has unknown status %s", jobID, status.Status)
+		}
+	}
+}
+
+func cancelJob(engine JobEngine, jobID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+	defer cancel()
+	if err := engine.Cancel(ctx, jobID); err != nil {
+		log.Error().Err(err).Msgf("[job] failed to cancel job %s", jobID)
+		return
+	}
+	log.Info().Msgf("[job] job %s is cancelled", jobID)
+}
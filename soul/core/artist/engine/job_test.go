@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/artchitector/artchitect/model"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testArtist - async artist, job is done after 3 status requests
+type testArtist struct {
+	mutex     sync.Mutex
+	polls     uint
+	form      map[string]string
+	cancelled bool
+	hang      bool // job never finishes
+}
+
+func (a *testArtist) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		a.mutex.Lock()
+		a.form = map[string]string{"seed": r.PostForm.Get("seed"), "preview": r.PostForm.Get("preview")}
+		a.mutex.Unlock()
+		_ = json.NewEncoder(w).Encode(JobStatus{ID: "j1", Status: JobStatusQueued})
+	})
+	mux.HandleFunc("/jobs/j1", func(w http.ResponseWriter, r *http.Request) {
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+		if r.Method == http.MethodDelete {
+			a.cancelled = true
+			return
+		}
+		a.polls++
+		status := JobStatus{ID: "j1", Status: JobStatusRunning, Step: a.polls * 10, TotalSteps: 30, Percent: float64(a.polls) * 100 / 3}
+		if r.URL.Query().Get("preview") == "true" {
+			status.Preview = base64.StdEncoding.EncodeToString(testPNG(t, 2, 3))
+		}
+		if a.polls >= 3 && !a.hang {
+			status.Status = JobStatusDone
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	})
+	mux.HandleFunc("/jobs/j1/result", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderArtistVersion, "2.0")
+		_, _ = w.Write(testPNG(t, 8, 12))
+	})
+	return mux
+}
+
+func TestArtistEngineJob(t *testing.T) {
+	artist := &testArtist{}
+	server := httptest.NewServer(artist.handler(t))
+	defer server.Close()
+
+	e := NewArtistEngine(server.URL, time.Second*5, true)
+	e.pollInterval = time.Millisecond
+	progress := make([]Progress, 0)
+	ctx := WithProgress(context.Background(), func(p Progress) {
+		progress = append(progress, p)
+	}, true)
+
+	img, info, err := e.GetImage(ctx, model.Spell{Seed: 42, Steps: 30})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if img.Bounds().Dx() != 8 || info.ArtistVersion != "2.0" {
+		t.Fatalf("unexpected result %s, %+v", img.Bounds(), info)
+	}
+	if artist.form["seed"] != "42" || artist.form["preview"] != "true" {
+		t.Fatalf("unexpected job form %+v", artist.form)
+	}
+	if len(progress) != 3 {
+		t.Fatalf("expected 2 running steps and done, got %+v", progress)
+	}
+	if progress[0].Step != 10 || progress[0].TotalSteps != 30 || progress[0].Preview == nil {
+		t.Fatalf("unexpected first progress %+v", progress[0])
+	}
+	if progress[2].Percent != 100 || progress[2].Step != 30 {
+		t.Fatalf("unexpected last progress %+v", progress[2])
+	}
+}
+
+func TestArtistEngineJobCancel(t *testing.T) {
+	artist := &testArtist{hang: true}
+	server := httptest.NewServer(artist.handler(t))
+	defer server.Close()
+
+	e := NewArtistEngine(server.URL, time.Millisecond*50, true)
+	e.pollInterval = time.Millisecond
+	if _, _, err := e.GetImage(context.Background(), model.Spell{}); err == nil {
+		t.Fatalf("expected timeout error")
+	}
+	artist.mutex.Lock()
+	defer artist.mutex.Unlock()
+	if !artist.cancelled {
+		t.Fatalf("job must be cancelled after timeout")
+	}
+	if artist.form["preview"] != "" {
+		t.Fatalf("preview must not be requested without listener")
+	}
+}
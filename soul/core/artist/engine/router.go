@@ -0,0 +1,236 @@
+package engine
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const DefaultHealthInterval = time.Second * 30
+
+// Backend - one engine of router (our artist, Automatic1111, ComfyUI, fake)
+type Backend interface {
+	GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error)
+	Ping(ctx context.Context) error
+}
+
+// BackendStats - health and latency of backend (latency is counted only for successful paintings)
+type BackendStats struct {
+	Name        string
+	Weight      uint
+	Healthy     bool
+	Requests    uint
+	Failures    uint
+	LastLatency time.Duration
+	AvgLatency  time.Duration
+	MaxLatency  time.Duration
+	LastError   string
+}
+
+type routedBackend struct {
+	name    string
+	weight  uint
+	backend Backend
+	models  map[string]string // Spell.EngineModel -> model of backend (nil - EngineModel is sent as is)
+
+	mutex        sync.Mutex
+	stats        BackendStats
+	totalLatency time.Duration
+}
+
+/*
+Router paints with several backends. Backend is chosen by weight among healthy ones.
+If painting fails (timeout, backend is down), the same spell is painted on the next backend, unhealthy backends are tried last.
+Failed backend is marked unhealthy until next successful health check (Run).
+Backend with models paints only spells of these models, Spell.EngineModel is translated to name of model on backend.
+*/
+type Router struct {
+	backends       []*routedBackend
+	healthInterval time.Duration
+	rnd            *rand.Rand
+	rndMutex       sync.Mutex
+}
+
+func NewRouter(healthInterval time.Duration) *Router {
+	return &Router{
+		backends:       make([]*routedBackend, 0),
+		healthInterval: healthInterval,
+		rnd:            rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// AddBackend adds backend with weight (0 - backend is used only as reserve, when others failed)
+func (r *Router) AddBackend(name string, weight uint, backend Backend) {
+	r.AddModelBackend(name, weight, backend, nil)
+}
+
+// AddModelBackend adds backend with its models (Spell.EngineModel -> model name of backend)
+func (r *Router) AddModelBackend(name string, weight uint, backend Backend, models map[string]string) {
+	r.backends = append(r.backends, &routedBackend{
+		name:    name,
+		weight:  weight,
+		backend: backend,
+		models:  models,
+		stats:   BackendStats{Name: name, Weight: weight, Healthy: true},
+	})
+}
+
+func (r *Router) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	if len(r.backends) == 0 {
+		return nil, model.EngineInfo{}, errors.New("[router] no backends")
+	}
+	var lastErr error
+	for _, b := range r.order() {
+		if ctx.Err() != nil {
+			return nil, model.EngineInfo{}, ctx.Err()
+		}
+		backendSpell, found := b.spell(spell)
+		if !found {
+			log.Warn().Msgf("[router] backend %s has no model %s, skip it", b.name, spell.EngineModel)
+			lastErr = errors.Errorf("[router] backend %s has no model %s", b.name, spell.EngineModel)
+			continue
+		}
+		start := time.Now()
+		img, info, err := b.backend.GetImage(ctx, backendSpell)
+		if err != nil && ctx.Err() != nil {
+			return nil, model.EngineInfo{}, ctx.Err() // soul is stopping, backend is not guilty
+		}
+		b.done(time.Since(start), err)
+		if err == nil {
+			log.Info().Msgf("[router] backend %s painted spell %d in %s", b.name, spell.ID, time.Since(start))
+			return img, info, nil
+		}
+		log.Error().Err(err).Msgf("[router] backend %s failed to paint spell %d, try next backend", b.name, spell.ID)
+		lastErr = err
+	}
+	return nil, model.EngineInfo{}, errors.Wrap(lastErr, "[router] all backends failed")
+}
+
+// Run checks health of all backends every healthInterval and logs their stats
+func (r *Router) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.healthInterval)
+	defer ticker.Stop()
+	for {
+		r.checkHealth(ctx)
+		for _, stats := range r.Stats() {
+			log.Info().Msgf("[router] backend %s: healthy=%t, requests=%d, failures=%d, avg=%s, max=%s",
+				stats.Name, stats.Healthy, stats.Requests, stats.Failures, stats.AvgLatency, stats.MaxLatency)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Router) Stats() []BackendStats {
+	stats := make([]BackendStats, 0, len(r.backends))
+	for _, b := range r.backends {
+		b.mutex.Lock()
+		stats = append(stats, b.stats)
+		b.mutex.Unlock()
+	}
+	return stats
+}
+
+func (r *Router) checkHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, b := range r.backends {
+		wg.Add(1)
+		go func(b *routedBackend) {
+			defer wg.Done()
+			err := b.backend.Ping(ctx)
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+			if err != nil && b.stats.Healthy {
+				log.Warn().Err(err).Msgf("[router] backend %s is unhealthy", b.name)
+			} else if err == nil && !b.stats.Healthy {
+				log.Info().Msgf("[router] backend %s is healthy again", b.name)
+			}
+			b.stats.Healthy = err == nil
+			if err != nil {
+				b.stats.LastError = err.Error()
+			}
+		}(b)
+	}
+	wg.Wait()
+}
+
+/*
+order - sequence of backends for one painting: healthy backends drawn one by one proportionally to weights,
+then healthy backends with zero weight, then unhealthy ones (maybe they are up again).
+*/
+func (r *Router) order() []*routedBackend {
+	weighted := make([]*routedBackend, 0, len(r.backends))
+	reserve := make([]*routedBackend, 0)
+	unhealthy := make([]*routedBackend, 0)
+	for _, b := range r.backends {
+		b.mutex.Lock()
+		healthy := b.stats.Healthy
+		b.mutex.Unlock()
+		switch {
+		case !healthy:
+			unhealthy = append(unhealthy, b)
+		case b.weight == 0:
+			reserve = append(reserve, b)
+		default:
+			weighted = append(weighted, b)
+		}
+	}
+
+	order := make([]*routedBackend, 0, len(r.backends))
+	r.rndMutex.Lock()
+	for len(weighted) > 0 {
+		var total uint
+		for _, b := range weighted {
+			total += b.weight
+		}
+		point := uint(r.rnd.Int63n(int64(total)))
+		for idx, b := range weighted {
+			if point < b.weight {
+				order = append(order, b)
+				weighted = append(weighted[:idx], weighted[idx+1:]...)
+				break
+			}
+			point -= b.weight
+		}
+	}
+	r.rndMutex.Unlock()
+	order = append(order, reserve...)
+	return append(order, unhealthy...)
+}
+
+// spell translates model of spell to model of backend (false - backend has no such model)
+func (b *routedBackend) spell(spell model.Spell) (model.Spell, bool) {
+	if b.models == nil || spell.EngineModel == "" {
+		return spell, true
+	}
+	name, found := b.models[spell.EngineModel]
+	spell.EngineModel = name
+	return spell, found
+}
+
+func (b *routedBackend) done(latency time.Duration, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.stats.Requests++
+	if err != nil {
+		b.stats.Failures++
+		b.stats.Healthy = false
+		b.stats.LastError = err.Error()
+		return
+	}
+	b.stats.Healthy = true
+	b.stats.LastLatency = latency
+	b.totalLatency += latency
+	b.stats.AvgLatency = b.totalLatency / time.Duration(b.stats.Requests-b.stats.Failures)
+	if latency > b.stats.MaxLatency {
+		b.stats.MaxLatency = latency
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"image"
+	"testing"
+	"time"
+)
+
+type testBackend struct {
+	err   error
+	calls int
+	model string // model of last spell
+}
+
+func (b *testBackend) GetImage(ctx context.Context, spell model.Spell) (image.Image, model.EngineInfo, error) {
+	b.calls++
+	b.model = spell.EngineModel
+	if b.err != nil {
+		return nil, model.EngineInfo{}, b.err
+	}
+	return image.NewRGBA(image.Rect(0, 0, 1, 1)), model.EngineInfo{ArtistVersion: "test"}, nil
+}
+
+func (b *testBackend) Ping(ctx context.Context) error {
+	return b.err
+}
+
+func TestRouterFailover(t *testing.T) {
+	broken := &testBackend{err: errors.New("timeout")}
+	reserve := &testBackend{}
+	router := NewRouter(time.Minute)
+	router.AddBackend("broken", 1, broken)
+	router.AddBackend("reserve", 0, reserve)
+
+	_, info, err := router.GetImage(context.Background(), model.Spell{})
+	if err != nil || info.ArtistVersion != "test" {
+		t.Fatalf("expected image from reserve backend, got %v", err)
+	}
+	if broken.calls != 1 || reserve.calls != 1 {
+		t.Fatalf("broken backend must be tried first, then reserve: %d, %d", broken.calls, reserve.calls)
+	}
+	stats := router.Stats()
+	if stats[0].Healthy || stats[0].Failures != 1 || !stats[1].Healthy || stats[1].Requests != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// unhealthy backend is tried after healthy reserve
+	if _, _, err := router.GetImage(context.Background(), model.Spell{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if broken.calls != 1 || reserve.calls != 2 {
+		t.Fatalf("unhealthy backend must be skipped while reserve works: %d, %d", broken.calls, reserve.calls)
+	}
+
+	reserve.err = errors.New("down")
+	if _, _, err := router.GetImage(context.Background(), model.Spell{}); err == nil {
+		t.Fatalf("expected error when all backends failed")
+	}
+	if broken.calls != 2 {
+		t.Fatalf("unhealthy backend must be tried when others failed")
+	}
+}
+
+func TestRouterWeights(t *testing.T) {
+	router := NewRouter(time.Minute)
+	heavy, light := &testBackend{}, &testBackend{}
+	router.AddBackend("heavy", 9, heavy)
+	router.AddBackend("light", 1, light)
+	for i := 0; i < 1000; i++ {
+		if _, _, err := router.GetImage(context.Background(), model.Spell{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if heavy.calls < 800 || light.calls < 50 {
+		t.Fatalf("backends are not chosen by weight: heavy=%d, light=%d", heavy.calls, light.calls)
+	}
+}
+
+func TestRouterModels(t *testing.T) {
+	invoke, webui := &testBackend{}, &testBackend{}
+	router := NewRouter(time.Minute)
+	router.AddModelBackend("invoke", 1, invoke, map[string]string{"sd-1.5": "stable-diffusion-1.5"})
+	router.AddModelBackend("webui", 0, webui, map[string]string{
+		"sd-1.5": "v1-5-pruned-emaonly.safetensors [6ce0161689]",
+		"sd-2.1": "v2-1_768-ema-pruned.safetensors [ad2a33c361]",
+	})
+
+	if _, _, err := router.GetImage(context.Background(), model.Spell{EngineModel: "sd-1.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if invoke.calls != 1 || invoke.model != "stable-diffusion-1.5" {
+		t.Fatalf("model must be translated for backend, got %d calls with %q", invoke.calls, invoke.model)
+	}
+
+	// invoke has no model, spell goes to reserve, which has it (invoke stays healthy)
+	if _, _, err := router.GetImage(context.Background(), model.Spell{EngineModel: "sd-2.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if invoke.calls != 1 || webui.calls != 1 || webui.model != "v2-1_768-ema-pruned.safetensors [ad2a33c361]" {
+		t.Fatalf("spell must be painted by backend with model: %d, %d (%q)", invoke.calls, webui.calls, webui.model)
+	}
+	if stats := router.Stats(); !stats[0].Healthy || stats[0].Failures != 0 {
+		t.Fatalf("backend without model must not fail: %+v", stats[0])
+	}
+
+	// spell without model is painted with default model of backend
+	if _, _, err := router.GetImage(context.Background(), model.Spell{}); err != nil || invoke.calls != 2 || invoke.model != "" {
+		t.Fatalf("spell without model must be painted as is, got %v, %q", err, invoke.model)
+	}
+	if _, _, err := router.GetImage(context.Background(), model.Spell{EngineModel: "sdxl"}); err == nil {
+		t.Fatalf("expected error, when no backend has model")
+	}
+}
@@ -3,6 +3,7 @@ package creator
 import (
 	"context"
 	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"sync"
@@ -15,10 +16,9 @@ type artist interface {
 }
 type speller interface {
 	MakeSpell(ctx context.Context, artistState *model.CreationState) (model.Spell, error)
+	MakeRemixSpell(ctx context.Context, kind string, parent model.Spell, other model.Spell, artistState *model.CreationState) (model.Spell, error)
 }
 type notifier interface {
-	NotifyPrehotCard(ctx context.Context, card model.Art) error
-	NotifyNewCard(ctx context.Context, card model.Art) error
 	NotifyCreationState(ctx context.Context, state model.CreationState) error
 }
 
@@ -40,7 +40,6 @@ type Creator struct {
 	unifier       unifier
 	maxCardGetter maxCardGetter
 	cardTotalTime uint // in seconds
-	prehotDelay   uint // in seconds
 }
 
 func NewCreator(
@@ -50,7 +49,6 @@ func NewCreator(
 	unifier unifier,
 	maxCardGetter maxCardGetter,
 	cardTotalTime uint,
-	prehotDelay uint,
 ) *Creator {
 	return &Creator{
 		sync.Mutex{},
@@ -60,7 +58,6 @@ func NewCreator(
 		unifier,
 		maxCardGetter,
 		cardTotalTime,
-		prehotDelay,
 	}
 }
 
@@ -75,11 +72,32 @@ func (c *Creator) CreateWithoutEnjoy(ctx context.Context) (model.Art, error) {
 		NextArtID: nextArtID,
 	}
 
-	card, err := c.create(ctx, nextArtID, &state)
+	card, err := c.create(ctx, nextArtID, &state, c.speller.MakeSpell)
 
 	return card, errors.Wrap(err, "[creator] failed to create card without enjoy")
 }
 
+// CreateRemix makes new art from spell of parent art (other art is used for model.RemixCrossover). New art keeps ParentID
+func (c *Creator) CreateRemix(ctx context.Context, kind string, parent model.Art, other model.Art) (model.Art, error) {
+	log.Info().Msgf("[creator] start %s remix of art %d", kind, parent.ID)
+
+	nextArtID, err := c.getNextArtID(ctx)
+	if err != nil {
+		return model.Art{}, errors.Wrap(err, "[creator] failed to get nextArtID")
+	}
+	state := model.CreationState{
+		NextArtID:   nextArtID,
+		ParentArtID: parent.ID,
+		Remix:       kind,
+	}
+
+	art, err := c.create(ctx, nextArtID, &state, func(ctx context.Context, state *model.CreationState) (model.Spell, error) {
+		return c.speller.MakeRemixSpell(ctx, kind, parent.Spell, other.Spell, state)
+	})
+
+	return art, errors.Wrapf(err, "[creator] failed to create remix of art %d", parent.ID)
+}
+
 func (c *Creator) CreateWithEnjoy(ctx context.Context) (model.Art, error) {
 	log.Info().Msgf("[creator] start art creation with enjoy")
 	artStart := time.Now()
@@ -99,7 +117,7 @@ func (c *Creator) CreateWithEnjoy(ctx context.Context) (model.Art, error) {
 		PreviousCardID: maxArtId,
 	}
 
-	art, err := c.create(ctx, nextArtID, &state)
+	art, err := c.create(ctx, nextArtID, &state, c.speller.MakeSpell)
 	if err != nil {
 		return model.Art{}, errors.Wrap(err, "[creator] failed to create art with enjoy")
 	}
@@ -111,7 +129,12 @@ func (c *Creator) CreateWithEnjoy(ctx context.Context) (model.Art, error) {
 	return art, nil
 }
 
-func (c *Creator) create(ctx context.Context, nextArtID uint, state *model.CreationState) (model.Art, error) {
+func (c *Creator) create(
+	ctx context.Context,
+	nextArtID uint,
+	state *model.CreationState,
+	makeSpell func(ctx context.Context, state *model.CreationState) (model.Spell, error),
+) (model.Art, error) {
 	log.Info().Msgf("[creator] CREATE NEW ART %d", nextArtID)
 	// only one creation process at same time
 	c.mutex.Lock()
@@ -122,8 +145,8 @@ func (c *Creator) create(ctx context.Context, nextArtID uint, state *model.Creat
 		log.Error().Err(err).Msgf("[creator] failed notify artist state")
 	}
 
-	// generate Spell (base for card)
-	spell, err := c.speller.MakeSpell(ctx, state)
+	// generate Spell (base for card). All entropy decisions of the spell are linked with the new art
+	spell, err := makeSpell(entropy.WithArtID(ctx, nextArtID), state)
 	if err != nil {
 		return model.Art{}, err
 	}
@@ -136,17 +159,7 @@ func (c *Creator) create(ctx context.Context, nextArtID uint, state *model.Creat
 	}
 	log.Info().Msgf("[creator] got card: id=%d, spell_id=%d", card.ID, spell.ID)
 
-	// notify prehot
-	if err := c.notifier.NotifyPrehotCard(ctx, card); err != nil {
-		log.Error().Err(err).Msgf("[creator] failed to notify new card")
-	}
-
-	// give time to prehot cache
-	<-time.After(time.Second * time.Duration(c.prehotDelay))
-	// notify new card created
-	if err := c.notifier.NotifyNewCard(ctx, card); err != nil {
-		log.Error().Err(err).Msgf("[creator] failed to notify new card")
-	}
+	// card is painted, its images are in spool. Uploader prehots and announces new card after upload
 
 	state.CardID = card.ID
 	state.LastCardPaintTime = state.CurrentCardPaintTime
@@ -0,0 +1,96 @@
+package entropy
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/rs/zerolog/log"
+	"strconv"
+)
+
+type decisionRepository interface {
+	SaveDecision(ctx context.Context, decision model.Decision) (model.Decision, error)
+}
+
+type auditKey int
+
+const (
+	auditKeyPurpose auditKey = iota
+	auditKeyArtID
+	auditKeyLabels
+	auditKeySample
+	auditKeyVerdict
+)
+
+/*
+WithPurpose помечает контекст целью выбора (model.DecisionPurpose*).
+Все Select, сделанные с этим контекстом, попадут в журнал решений с этой целью.
+*/
+func WithPurpose(ctx context.Context, purpose string) context.Context {
+	return context.WithValue(ctx, auditKeyPurpose, purpose)
+}
+
+// WithArtID привязывает решения к карточке, ради которой они принимаются
+func WithArtID(ctx context.Context, artID uint) context.Context {
+	return context.WithValue(ctx, auditKeyArtID, artID)
+}
+
+/*
+WithLabels - названия вариантов выбора (теги словаря, версии). Выбранное название попадёт в журнал решений,
+так по журналу видно не только индекс, но и что именно было выбрано.
+*/
+func WithLabels(ctx context.Context, labels []string) context.Context {
+	return context.WithValue(ctx, auditKeyLabels, labels)
+}
+
+// WithSample - сырое значение, давшее результат выбора, будет записано в sample (для provenance карточки: сырое значение сида)
+func WithSample(ctx context.Context, sample *Sample) context.Context {
+	return context.WithValue(ctx, auditKeySample, sample)
+}
+
+/*
+WithVerdict - проверка, будет ли выбранный вариант использован (тег может конфликтовать с правилами словаря).
+Вызывается сразу после выбора, до записи решения, поэтому в журнале решений видно, какие варианты отброшены.
+*/
+func WithVerdict(ctx context.Context, verdict func(result uint) bool) context.Context {
+	return context.WithValue(ctx, auditKeyVerdict, verdict)
+}
+
+// audit - point: выбранная точка в [0, total), для SelectWeighted она попадает в вес варианта result
+func (e *Entropy) audit(ctx context.Context, total uint, point uint, result uint, sample Sample, attempts int) {
+	if out, _ := ctx.Value(auditKeySample).(*Sample); out != nil {
+		*out = sample
+	}
+	rejected := false
+	if verdict, _ := ctx.Value(auditKeyVerdict).(func(result uint) bool); verdict != nil {
+		rejected = !verdict(result)
+	}
+	if e.decisions == nil {
+		return
+	}
+	purpose, _ := ctx.Value(auditKeyPurpose).(string)
+	if purpose == "" {
+		purpose = model.DecisionPurposeUnknown
+	}
+	artID, _ := ctx.Value(auditKeyArtID).(uint)
+	var label string
+	if labels, _ := ctx.Value(auditKeyLabels).([]string); result < uint(len(labels)) {
+		label = labels[result]
+	}
+
+	decision := model.Decision{
+		Purpose:   purpose,
+		ArtID:     artID,
+		Total:     total,
+		Point:     point,
+		Result:    result,
+		Label:     label,
+		Raw:       strconv.FormatUint(sample.Value, 10),
+		Attempts:  uint(attempts),
+		Rejected:  rejected,
+		FrameTime: sample.FrameTime,
+	}
+	// журнал не должен ломать творение, поэтому ошибка только логируется
+	if _, err := e.decisions.SaveDecision(ctx, decision); err != nil {
+		log.Error().Err(err).Msgf("[entropy] failed to save decision %s (art=%d)", purpose, artID)
+	}
+}
@@ -0,0 +1,103 @@
+package entropy
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type testDecisionRepository struct {
+	decisions []model.Decision
+	fail      bool
+}
+
+func (r *testDecisionRepository) SaveDecision(ctx context.Context, decision model.Decision) (model.Decision, error) {
+	if r.fail {
+		return model.Decision{}, errors.New("database is down")
+	}
+	decision.ID = uint(len(r.decisions) + 1)
+	r.decisions = append(r.decisions, decision)
+	return decision, nil
+}
+
+// frameSource отдаёт значения с временем кадра, как Lightmaster
+type frameSource struct {
+	sequenceSource
+	frameTime time.Time
+}
+
+func (s *frameSource) GetChoice(ctx context.Context) (Sample, error) {
+	sample, err := s.sequenceSource.GetChoice(ctx)
+	sample.FrameTime = s.frameTime
+	return sample, err
+}
+
+func TestAudit(t *testing.T) {
+	frameTime := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	repo := &testDecisionRepository{}
+	// 2^64 mod 3 == 1: первое значение отбрасывается
+	e := NewEntropy(&frameSource{sequenceSource{values: []uint64{0, 5, 100}}, frameTime}, NewHealth(nil), false, repo)
+
+	var sample Sample
+	ctx := WithPurpose(context.Background(), model.DecisionPurposeTag)
+	ctx = WithArtID(ctx, 77)
+	ctx = WithLabels(ctx, []string{"sun", "moon", "star"})
+	ctx = WithSample(ctx, &sample)
+	selected, err := e.Select(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selected != 2 || sample.Value != 5 || !sample.FrameTime.Equal(frameTime) {
+		t.Fatalf("unexpected selection %d, sample %+v", selected, sample)
+	}
+	if len(repo.decisions) != 1 {
+		t.Fatalf("expected 1 decision, got %d", len(repo.decisions))
+	}
+	expected := model.Decision{
+		ID:        1,
+		Purpose:   model.DecisionPurposeTag,
+		ArtID:     77,
+		Total:     3,
+		Point:     2,
+		Result:    2,
+		Label:     "star",
+		Raw:       strconv.FormatUint(5, 10),
+		Attempts:  2,
+		FrameTime: frameTime,
+	}
+	if repo.decisions[0] != expected {
+		t.Fatalf("expected decision %+v, got %+v", expected, repo.decisions[0])
+	}
+
+	// без цели и меток решение всё равно пишется
+	if _, err := e.Select(context.Background(), 10); err != nil {
+		t.Fatal(err)
+	}
+	if d := repo.decisions[1]; d.Purpose != model.DecisionPurposeUnknown || d.ArtID != 0 || d.Label != "" {
+		t.Fatalf("unexpected decision without context %+v", d)
+	}
+
+	// сломанный журнал не ломает выбор
+	repo.fail = true
+	if _, err := e.Select(ctx, 3); err != nil {
+		t.Fatalf("failed audit must not fail selection: %s", err)
+	}
+}
+
+func TestAuditWeighted(t *testing.T) {
+	repo := &testDecisionRepository{}
+	// точка 7 из суммарного веса 10 попадает в вес третьего варианта: [0,2) [2,5) [5,10)
+	e := NewEntropy(&sequenceSource{values: []uint64{7}}, NewHealth(nil), false, repo)
+	ctx := WithVerdict(context.Background(), func(result uint) bool { return result != 2 })
+	selected, err := e.SelectWeighted(ctx, []uint{2, 3, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := repo.decisions[0]
+	if selected != 2 || d.Total != 10 || d.Point != 7 || d.Result != 2 || d.Raw != "7" || !d.Rejected {
+		t.Fatalf("unexpected weighted decision %+v", d)
+	}
+}
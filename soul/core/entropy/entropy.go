@@ -2,25 +2,146 @@ package entropy
 
 import (
 	"context"
+	"github.com/pkg/errors"
 	"math"
+	"time"
 )
 
+// MaxSelectAttempts - rejection sampling отбрасывает меньше половины значений даже в худшем случае,
+// так что 64 подряд отброшенных значения означают сломанный источник
+const MaxSelectAttempts = 64
+
+/*
+EntropySource - источник энтропии для Artchitect.
+Основной источник - Lightmaster (шум света с веб-камеры или из записанных кадров),
+но для стендов без камеры можно использовать /dev/urandom или детерминированный PRNG.
+Значения - сырые 64 бита.
+*/
+type EntropySource interface {
+	StartEntropyReading(ctx context.Context) error
+	GetEntropy(ctx context.Context) (Sample, error)
+	GetChoice(ctx context.Context) (Sample, error)
+}
+
+// Sample - сырое значение и время кадра, из которого оно получено (для источников без кадров - время генерации)
+type Sample struct {
+	Value     uint64
+	FrameTime time.Time
+}
+
 type Entropy struct {
-	lightmaster *Lightmaster
+	source       EntropySource
+	health       *Health
+	strictHealth bool               // refuse Select while entropy stream is unhealthy
+	decisions    decisionRepository // nil - audit log disabled
 }
 
-func NewEntropy(lightmaster *Lightmaster) *Entropy {
-	return &Entropy{lightmaster: lightmaster}
+func NewEntropy(source EntropySource, health *Health, strictHealth bool, decisions decisionRepository) *Entropy {
+	return &Entropy{source, health, strictHealth, decisions}
 }
 
 /*
 	Artchitect asks "select one element from set, i have total 100 elements.
 	Entropy replies: "take element 31" (calculated with the lightnoise-entropy)
+
+	Выбор делается rejection sampling-ом по сырым битам: значения из "хвоста" [0, 2^64 mod n) отбрасываются,
+	поэтому каждый индекс из [0, n) выпадает ровно с одинаковой вероятностью.
 */
 
 func (e *Entropy) Select(ctx context.Context, totalElements uint) (uint, error) {
-	entropyF := e.lightmaster.GetChoice(ctx)
-	targetIndex := uint(math.Floor(float64(totalElements) * entropyF))
+	result, sample, attempts, err := e.selectIndex(ctx, totalElements)
+	if err != nil {
+		return 0, err
+	}
+	e.audit(ctx, totalElements, result, result, sample, attempts)
+	return result, nil
+}
+
+// selectIndex - Select без записи в журнал решений (возвращает сырое значение и число попыток для журнала)
+func (e *Entropy) selectIndex(ctx context.Context, totalElements uint) (uint, Sample, int, error) {
+	if totalElements == 0 {
+		return 0, Sample{}, 0, errors.New("[entropy] nothing to select from, totalElements=0")
+	}
+	if e.strictHealth && !e.health.IsHealthy() {
+		return 0, Sample{}, 0, ErrEntropyUnhealthy
+	}
+
+	n := uint64(totalElements)
+	threshold := -n % n // == 2^64 mod n
+	for attempt := 0; attempt < MaxSelectAttempts; attempt++ {
+		sample, err := e.source.GetChoice(ctx)
+		if err != nil {
+			return 0, Sample{}, 0, errors.Wrapf(err, "[entropy] failed to get choice for totalElements=%d", totalElements)
+		}
+		if sample.Value >= threshold {
+			return uint(sample.Value % n), sample, attempt + 1, nil
+		}
+	}
+	return 0, Sample{}, 0, errors.Errorf("[entropy] all %d values rejected for totalElements=%d", MaxSelectAttempts, totalElements)
+}
+
+/*
+SelectN выбирает count разных индексов из [0, totalElements) (выборка без возвращения).
+Порядок результата тоже случайный (частичная перетасовка Фишера-Йетса, без выделения памяти на весь диапазон).
+*/
+func (e *Entropy) SelectN(ctx context.Context, totalElements uint, count uint) ([]uint, error) {
+	if count > totalElements {
+		return nil, errors.Errorf("[entropy] cannot select %d elements from %d without replacement", count, totalElements)
+	}
+	swapped := make(map[uint]uint, count)
+	get := func(idx uint) uint {
+		if v, found := swapped[idx]; found {
+			return v
+		}
+		return idx
+	}
+
+	result := make([]uint, 0, count)
+	for i := uint(0); i < count; i++ {
+		offset, err := e.Select(ctx, totalElements-i)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[entropy] failed to select element %d of %d", i, count)
+		}
+		j := i + offset
+		selected := get(j)
+		swapped[j] = get(i)
+		result = append(result, selected)
+	}
+	return result, nil
+}
+
+/*
+SelectWeighted выбирает индекс с вероятностью, пропорциональной его весу (веса целые, чтобы выбор был точным).
+Элементы с весом 0 никогда не выбираются. В журнал решений пишется суммарный вес, выбранная точка и выбранный индекс.
+*/
+func (e *Entropy) SelectWeighted(ctx context.Context, weights []uint) (uint, error) {
+	var total uint
+	for idx, weight := range weights {
+		if total+weight < total {
+			return 0, errors.Errorf("[entropy] weights overflow on index %d", idx)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return 0, errors.New("[entropy] nothing to select from, total weight=0")
+	}
+
+	point, sample, attempts, err := e.selectIndex(ctx, total)
+	if err != nil {
+		return 0, errors.Wrap(err, "[entropy] failed to select weighted point")
+	}
+	rest := point
+	for idx, weight := range weights {
+		if rest < weight {
+			e.audit(ctx, total, point, uint(idx), sample, attempts)
+			return uint(idx), nil
+		}
+		rest -= weight
+	}
+	return 0, errors.Errorf("[entropy] weighted point out of range") // unreachable
+}
 
-	return targetIndex, nil
+// uint64ToFloat используется только для отображения (EntropyValue.Float64), не для решений
+func uint64ToFloat(value uint64) float64 {
+	return float64(value) / float64(math.MaxUint64)
 }
@@ -0,0 +1,107 @@
+package entropy
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// sequenceSource отдаёт заранее заданные значения по кругу
+type sequenceSource struct {
+	values []uint64
+	i      int
+}
+
+func (s *sequenceSource) StartEntropyReading(ctx context.Context) error  { return nil }
+func (s *sequenceSource) GetEntropy(ctx context.Context) (Sample, error) { return s.GetChoice(ctx) }
+func (s *sequenceSource) GetChoice(ctx context.Context) (Sample, error) {
+	value := s.values[s.i%len(s.values)]
+	s.i += 1
+	return Sample{Value: value}, nil
+}
+
+func newTestEntropy(values ...uint64) *Entropy {
+	return NewEntropy(&sequenceSource{values: values}, NewHealth(nil), false, nil)
+}
+
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []uint64
+		total    uint
+		expected uint
+		err      bool
+	}{
+		{name: "max value is in range", values: []uint64{math.MaxUint64}, total: 100, expected: math.MaxUint64 % 100},
+		{name: "max seed", values: []uint64{math.MaxUint64}, total: 4294967295, expected: 0},
+		{name: "single element", values: []uint64{12345}, total: 1, expected: 0},
+		// 2^64 mod 3 == 1, поэтому значение 0 отбрасывается и берётся следующее
+		{name: "rejected tail", values: []uint64{0, 5}, total: 3, expected: 2},
+		{name: "all rejected", values: []uint64{0}, total: 3, err: true},
+		{name: "empty set", values: []uint64{1}, total: 0, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, err := newTestEntropy(tc.values...).Select(context.Background(), tc.total)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected error, got %d", selected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if selected != tc.expected {
+				t.Fatalf("expected %d, got %d", tc.expected, selected)
+			}
+		})
+	}
+}
+
+func TestSelectN(t *testing.T) {
+	e := NewEntropy(NewPrngSource(7), NewHealth(nil), false, nil)
+	selected, err := e.SelectN(context.Background(), 10, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	seen := make(map[uint]bool)
+	for _, idx := range selected {
+		if idx >= 10 || seen[idx] {
+			t.Fatalf("expected permutation of [0,10), got %v", selected)
+		}
+		seen[idx] = true
+	}
+
+	if _, err := e.SelectN(context.Background(), 3, 4); err == nil {
+		t.Fatalf("expected error when selecting more than total")
+	}
+}
+
+func TestSelectWeighted(t *testing.T) {
+	// total weight 6: points 0 -> idx 0, 1..3 -> idx 2 (weight of idx 1 is zero), 4..5 -> idx 3
+	testCases := []struct {
+		value    uint64
+		expected uint
+	}{
+		{value: 6 * 1000, expected: 0},
+		{value: 6*1000 + 1, expected: 2},
+		{value: 6*1000 + 3, expected: 2},
+		{value: 6*1000 + 4, expected: 3},
+		{value: 6*1000 + 5, expected: 3},
+	}
+	for _, tc := range testCases {
+		selected, err := newTestEntropy(tc.value).SelectWeighted(context.Background(), []uint{1, 0, 3, 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if selected != tc.expected {
+			t.Errorf("value %d: expected %d, got %d", tc.value, tc.expected, selected)
+		}
+	}
+
+	if _, err := newTestEntropy(1).SelectWeighted(context.Background(), []uint{0, 0}); err == nil {
+		t.Fatalf("expected error on zero weights")
+	}
+}
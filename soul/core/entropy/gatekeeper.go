@@ -14,6 +14,7 @@ import (
 
 type notifier interface {
 	NotifyEntropy(ctx context.Context, state model.EntropyState) error
+	NotifyEntropyHealth(ctx context.Context, health model.EntropyHealth) error
 }
 
 /*
@@ -0,0 +1,280 @@
+package entropy
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"math"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	HealthStreamEntropy = "entropy" // значения noiseToEntropy
+	HealthStreamChoice  = "choice"  // значения invertEntropy
+
+	HealthWindow       = 256 // сколько последних uint64 участвуют в статистических тестах (16384 бит, 2048 байт)
+	HealthNotifyPeriod = 25  // раз в сколько кадров публиковать статус здоровья
+
+	// статистические тесты (monobit, runs) проваливаются при p-value меньше этого значения
+	HealthMinPValue = 0.001
+	// критическое значение chi-square для 255 степеней свободы при p=0.001
+	HealthChiSquareCritical = 330.52
+	// repetition count test (SP 800-90B 4.4.1): C = 1 + ceil(20/H), alpha=2^-20,
+	// оценка min-entropy H взята консервативно - 8 бит на 64-битное значение
+	HealthRepetitionCutoff = 4
+	// adaptive proportion test (SP 800-90B 4.4.2) по байтам: окно 512 байт, H=4 бита на байт, alpha=2^-20
+	HealthAdaptiveWindow = 512
+	HealthAdaptiveCutoff = 60
+)
+
+var ErrEntropyUnhealthy = errors.New("[entropy] entropy stream is unhealthy")
+
+/*
+Health постоянно проверяет поток значений lightmaster-а на случайность.
+Когда камера закрыта или сцена статична, поток деградирует (значения повторяются, биты перекошены),
+и это надо заметить: статус публикуется на gate (канал entropy_health), а Entropy.Select может отказаться выбирать.
+*/
+type Health struct {
+	mutex    sync.Mutex
+	notifier notifier
+	streams  map[string]*healthChecker
+	counter  uint
+	healthy  bool
+}
+
+func NewHealth(notifier notifier) *Health {
+	return &Health{
+		sync.Mutex{},
+		notifier,
+		map[string]*healthChecker{
+			HealthStreamEntropy: newHealthChecker(HealthStreamEntropy),
+			HealthStreamChoice:  newHealthChecker(HealthStreamChoice),
+		},
+		0,
+		true,
+	}
+}
+
+// IsHealthy - пока окно не набрано (прогрев), поток считается здоровым
+func (h *Health) IsHealthy() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.healthy
+}
+
+// Add добавляет значения одного кадра и раз в HealthNotifyPeriod кадров (или при смене статуса) публикует статус
+func (h *Health) Add(ctx context.Context, entropyValue uint64, choiceValue uint64) {
+	h.mutex.Lock()
+	h.streams[HealthStreamEntropy].add(entropyValue)
+	h.streams[HealthStreamChoice].add(choiceValue)
+	h.counter += 1
+	counter := h.counter
+
+	status := h.status()
+	changed := status.Healthy != h.healthy
+	h.healthy = status.Healthy
+	h.mutex.Unlock()
+
+	if changed && !status.Healthy {
+		log.Warn().Msgf("[health] entropy stream degraded: %+v", status.Streams)
+	} else if changed {
+		log.Info().Msgf("[health] entropy stream recovered")
+	}
+	if changed || counter%HealthNotifyPeriod == 0 {
+		if err := h.notifier.NotifyEntropyHealth(ctx, status); err != nil {
+			log.Error().Err(err).Msgf("[health] failed to notify entropy health")
+		}
+	}
+}
+
+func (h *Health) Status() model.EntropyHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.status()
+}
+
+func (h *Health) status() model.EntropyHealth {
+	status := model.EntropyHealth{
+		Timestamp: time.Now(),
+		Healthy:   true,
+	}
+	for _, name := range []string{HealthStreamEntropy, HealthStreamChoice} {
+		streamHealth := h.streams[name].check()
+		status.Healthy = status.Healthy && streamHealth.Healthy
+		status.Streams = append(status.Streams, streamHealth)
+	}
+	return status
+}
+
+// healthChecker держит окно значений одного потока и считает по нему тесты
+type healthChecker struct {
+	name   string
+	window []uint64 // кольцевой буфер
+	next   int
+	filled bool
+	total  uint
+
+	lastValue       uint64
+	repetitionCount int
+
+	adaptiveSample    byte
+	adaptiveSeen      int
+	adaptiveCount     int // в текущем окне
+	lastAdaptiveCount int // в последнем завершённом окне
+}
+
+func newHealthChecker(name string) *healthChecker {
+	return &healthChecker{name: name, window: make([]uint64, HealthWindow)}
+}
+
+func (hc *healthChecker) add(value uint64) {
+	// repetition count test - непрерывный, считается на каждом значении
+	if hc.total > 0 && value == hc.lastValue {
+		hc.repetitionCount += 1
+	} else {
+		hc.repetitionCount = 1
+	}
+	hc.lastValue = value
+	hc.total += 1
+
+	// adaptive proportion test - непрерывный, по байтам значения
+	for i := 0; i < 8; i++ {
+		b := byte(value >> (56 - 8*i))
+		if hc.adaptiveSeen == 0 {
+			hc.adaptiveSample = b
+			hc.adaptiveCount = 0
+		}
+		if b == hc.adaptiveSample {
+			hc.adaptiveCount += 1
+		}
+		hc.adaptiveSeen += 1
+		if hc.adaptiveSeen >= HealthAdaptiveWindow {
+			hc.lastAdaptiveCount = hc.adaptiveCount
+			hc.adaptiveSeen = 0
+		}
+	}
+
+	hc.window[hc.next] = value
+	hc.next += 1
+	if hc.next >= len(hc.window) {
+		hc.next = 0
+		hc.filled = true
+	}
+}
+
+func (hc *healthChecker) check() model.EntropyStreamHealth {
+	result := model.EntropyStreamHealth{
+		Name:               hc.name,
+		Healthy:            true,
+		Samples:            hc.samples(),
+		Failures:           []string{},
+		RepetitionCount:    hc.repetitionCount,
+		AdaptiveProportion: hc.adaptiveCount,
+	}
+	if !hc.filled {
+		result.WarmingUp = true
+		return result
+	}
+
+	values := hc.ordered()
+	result.Monobit = monobitTest(values)
+	result.Runs = runsTest(values)
+	result.ChiSquare = chiSquareBytesTest(values)
+
+	if result.Monobit < HealthMinPValue {
+		result.Failures = append(result.Failures, "monobit")
+	}
+	if result.Runs < HealthMinPValue {
+		result.Failures = append(result.Failures, "runs")
+	}
+	if result.ChiSquare > HealthChiSquareCritical {
+		result.Failures = append(result.Failures, "chi_square")
+	}
+	if hc.repetitionCount >= HealthRepetitionCutoff {
+		result.Failures = append(result.Failures, "repetition_count")
+	}
+	if hc.adaptiveCount >= HealthAdaptiveCutoff || hc.lastAdaptiveCount >= HealthAdaptiveCutoff {
+		result.Failures = append(result.Failures, "adaptive_proportion")
+	}
+	result.Healthy = len(result.Failures) == 0
+	return result
+}
+
+// ordered возвращает окно в хронологическом порядке (для runs test важен порядок бит)
+func (hc *healthChecker) ordered() []uint64 {
+	if !hc.filled {
+		return hc.window[:hc.next]
+	}
+	values := make([]uint64, 0, len(hc.window))
+	values = append(values, hc.window[hc.next:]...)
+	values = append(values, hc.window[:hc.next]...)
+	return values
+}
+
+func (hc *healthChecker) samples() int {
+	if hc.filled {
+		return len(hc.window)
+	}
+	return hc.next
+}
+
+// monobitTest - NIST SP 800-22 2.1, возвращает p-value
+func monobitTest(values []uint64) float64 {
+	n := float64(len(values) * 64)
+	ones := 0
+	for _, v := range values {
+		ones += bits.OnesCount64(v)
+	}
+	s := math.Abs(float64(2*ones)-n) / math.Sqrt(n)
+	return math.Erfc(s / math.Sqrt2)
+}
+
+// runsTest - NIST SP 800-22 2.3, возвращает p-value (0, если не выполнено предварительное условие по monobit)
+func runsTest(values []uint64) float64 {
+	n := float64(len(values) * 64)
+	ones := 0
+	for _, v := range values {
+		ones += bits.OnesCount64(v)
+	}
+	pi := float64(ones) / n
+	if math.Abs(pi-0.5) >= 2/math.Sqrt(n) {
+		return 0.0
+	}
+
+	runs := 1
+	var prev uint64
+	first := true
+	for _, v := range values {
+		for i := 63; i >= 0; i-- {
+			bit := (v >> uint(i)) & 1
+			if !first && bit != prev {
+				runs += 1
+			}
+			prev = bit
+			first = false
+		}
+	}
+	numerator := math.Abs(float64(runs) - 2*n*pi*(1-pi))
+	denominator := 2 * math.Sqrt(2*n) * pi * (1 - pi)
+	return math.Erfc(numerator / denominator)
+}
+
+// chiSquareBytesTest - статистика chi-square по 256 корзинам байтов (255 степеней свободы)
+func chiSquareBytesTest(values []uint64) float64 {
+	buckets := make([]int, 256)
+	for _, v := range values {
+		for i := 0; i < 8; i++ {
+			buckets[byte(v>>(8*i))] += 1
+		}
+	}
+	expected := float64(len(values)*8) / 256.0
+	var chi float64
+	for _, observed := range buckets {
+		d := float64(observed) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
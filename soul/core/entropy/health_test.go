@@ -0,0 +1,71 @@
+package entropy
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHealthChecker(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+
+	testCases := []struct {
+		name     string
+		next     func(i int) uint64
+		healthy  bool
+		failures []string
+	}{
+		{
+			name:    "prng stream",
+			next:    func(i int) uint64 { return rnd.Uint64() },
+			healthy: true,
+		},
+		{
+			name:     "covered camera",
+			next:     func(i int) uint64 { return 0 },
+			healthy:  false,
+			failures: []string{"monobit", "runs", "chi_square", "repetition_count", "adaptive_proportion"},
+		},
+		{
+			name:     "biased bits",
+			next:     func(i int) uint64 { return rnd.Uint64() | rnd.Uint64() },
+			healthy:  false,
+			failures: []string{"monobit", "runs"},
+		},
+		{
+			name:     "static scene (alternating two values)",
+			next:     func(i int) uint64 { return []uint64{0xF0F0F0F0F0F0F0F0, 0x0F0F0F0F0F0F0F0F}[i%2] },
+			healthy:  false,
+			failures: []string{"chi_square", "adaptive_proportion"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := newHealthChecker(HealthStreamChoice)
+			if !hc.check().WarmingUp {
+				t.Fatalf("expected warming up before window is filled")
+			}
+			for i := 0; i < HealthWindow*2; i++ {
+				hc.add(tc.next(i))
+			}
+			result := hc.check()
+			if result.Healthy != tc.healthy {
+				t.Fatalf("expected healthy=%t, got %+v", tc.healthy, result)
+			}
+			for _, failure := range tc.failures {
+				if !contains(result.Failures, failure) {
+					t.Errorf("expected failure %s, got %v", failure, result.Failures)
+				}
+			}
+		})
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package entropy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	JournalFile         = "journal.jsonl"
+	JournalSyncInterval = time.Second // журнал сбрасывается на диск не реже раза в секунду (и при Close)
+
+	JournalKindFrame   = "frame"   // кадр, который обработал lightmaster (и полученные из него значения)
+	JournalKindEntropy = "entropy" // значение entropy, которое кто-то забрал через GetEntropy
+	JournalKindChoice  = "choice"  // значение choice, которое кто-то забрал через GetChoice (именно оно принимает решения)
+)
+
+// JournalRecord - одна строка журнала (jsonl)
+type JournalRecord struct {
+	Kind       string
+	Timestamp  time.Time
+	Frame      string    `json:",omitempty"` // имя png-файла с кадром (только для frame)
+	Computed   bool      `json:",omitempty"` // для frame: были ли получены значения (первые кадры только копятся)
+	Entropy    uint64    `json:",omitempty"`
+	Choice     uint64    `json:",omitempty"`
+	RawEntropy uint64    `json:",omitempty"` // для frame: биты до conditioning
+	RawChoice  uint64    `json:",omitempty"`
+	Value      uint64    `json:",omitempty"` // для entropy/choice: выданное значение
+	FrameTime  time.Time `json:",omitempty"` // для entropy/choice: время кадра, из которого получено значение
+}
+
+/*
+Recorder пишет журнал сессии lightmaster: каждый обработанный кадр
+и каждое выданное наружу значение. По журналу можно объяснить, почему у карточки такой seed и такие теги,
+а JournalSource может проиграть сессию заново бит-в-бит.
+
+Каждый запуск soul пишет в свою поддиректорию (по времени старта).
+Png-кадры всех сессий в baseDir ограничены по суммарному размеру (maxSize) и возрасту (maxAge): старые кадры удаляются,
+в журнале остаётся только их имя (для replay кадры не нужны, значения есть в журнале). 0 - без ограничения.
+*/
+type Recorder struct {
+	mutex        sync.Mutex
+	dir          string
+	journal      *os.File
+	frameCounter uint
+	lastSync     time.Time
+
+	maxSize    int64
+	maxAge     time.Duration
+	frames     []recordedFrame // записанные кадры от старых к новым
+	framesSize int64
+}
+
+type recordedFrame struct {
+	path    string
+	size    int64
+	created time.Time
+}
+
+func NewRecorder(baseDir string, maxSize int64, maxAge time.Duration) (*Recorder, error) {
+	// кадры прошлых сессий тоже занимают место, поэтому попадают под ограничения
+	frames, err := findFrames(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := sessionDir(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(filepath.Join(dir, JournalFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[recorder] failed to open journal in %s", dir)
+	}
+	r := &Recorder{dir: dir, journal: journal, lastSync: time.Now(), maxSize: maxSize, maxAge: maxAge, frames: frames}
+	for _, frame := range frames {
+		r.framesSize += frame.size
+	}
+	r.prune()
+	log.Info().Msgf("[recorder] recording entropy session into %s (frames: %d, %d bytes)", dir, len(r.frames), r.framesSize)
+	return r, nil
+}
+
+// sessionDir создаёт директорию сессии, сессии одной секунды (перезапуск soul) не пишут в одну директорию
+func sessionDir(baseDir string) (string, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", errors.Wrapf(err, "[recorder] failed to create dir %s", baseDir)
+	}
+	name := time.Now().Format("20060102-150405")
+	dir := filepath.Join(baseDir, name)
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		} else if !os.IsExist(err) {
+			return "", errors.Wrapf(err, "[recorder] failed to create dir %s", dir)
+		}
+		dir = filepath.Join(baseDir, fmt.Sprintf("%s-%d", name, i))
+	}
+}
+
+// findFrames находит png-кадры всех сессий в baseDir, от старых к новым
+func findFrames(baseDir string) ([]recordedFrame, error) {
+	paths, err := filepath.Glob(filepath.Join(baseDir, "*", "frame-*.png"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "[recorder] failed to find frames in %s", baseDir)
+	}
+	frames := make([]recordedFrame, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // кадр удалён между Glob и Stat
+		}
+		frames = append(frames, recordedFrame{path, info.Size(), info.ModTime()})
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].created.Before(frames[j].created)
+	})
+	return frames, nil
+}
+
+func (r *Recorder) RecordFrame(frame image.Image, state model.EntropyState, computed bool, frameTime time.Time) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.frameCounter += 1
+	filename := fmt.Sprintf("frame-%08d.png", r.frameCounter)
+	if err := r.writeFrame(filename, frame); err != nil {
+		return err
+	}
+
+	err := r.write(JournalRecord{
+		Kind:       JournalKindFrame,
+		Timestamp:  frameTime,
+		Frame:      filename,
+		Computed:   computed,
+		Entropy:    state.Entropy.Uint64,
+		Choice:     state.Choice.Uint64,
+		RawEntropy: state.RawEntropy.Uint64,
+		RawChoice:  state.RawChoice.Uint64,
+	})
+	if err != nil {
+		return err
+	}
+	if time.Since(r.lastSync) >= JournalSyncInterval {
+		r.lastSync = time.Now()
+		if err := r.journal.Sync(); err != nil {
+			return errors.Wrap(err, "[recorder] failed to sync journal")
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) writeFrame(filename string, frame image.Image) error {
+	path := filepath.Join(r.dir, filename)
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrapf(err, "[recorder] failed to create frame file %s", filename)
+	}
+	defer f.Close()
+	if err := png.Encode(f, frame); err != nil {
+		return errors.Wrapf(err, "[recorder] failed to encode frame %s", filename)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrapf(err, "[recorder] failed to stat frame %s", filename)
+	}
+	r.frames = append(r.frames, recordedFrame{path, info.Size(), time.Now()})
+	r.framesSize += info.Size()
+	r.prune()
+	return nil
+}
+
+// prune удаляет самые старые кадры, пока кадры не уложатся в maxSize и maxAge
+func (r *Recorder) prune() {
+	removed := 0
+	for removed < len(r.frames) {
+		frame := r.frames[removed]
+		tooBig := r.maxSize > 0 && r.framesSize > r.maxSize
+		tooOld := r.maxAge > 0 && time.Since(frame.created) > r.maxAge
+		if !tooBig && !tooOld {
+			break
+		}
+		if err := os.Remove(frame.path); err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Msgf("[recorder] failed to remove old frame %s", frame.path)
+		}
+		r.framesSize -= frame.size
+		removed++
+	}
+	r.frames = r.frames[removed:]
+}
+
+// Close сбрасывает журнал на диск и закрывает его, вызывается при остановке soul
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.journal.Sync(); err != nil {
+		r.journal.Close()
+		return errors.Wrap(err, "[recorder] failed to sync journal")
+	}
+	if err := r.journal.Close(); err != nil {
+		return errors.Wrap(err, "[recorder] failed to close journal")
+	}
+	return nil
+}
+
+func (r *Recorder) RecordUsage(kind string, sample Sample) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.write(JournalRecord{
+		Kind:      kind,
+		Timestamp: time.Now(),
+		Value:     sample.Value,
+		FrameTime: sample.FrameTime,
+	})
+}
+
+func (r *Recorder) write(record JournalRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrapf(err, "[recorder] failed to marshal %s record", record.Kind)
+	}
+	line = append(line, '\n')
+	if _, err := r.journal.Write(line); err != nil {
+		return errors.Wrapf(err, "[recorder] failed to write %s record", record.Kind)
+	}
+	return nil
+}
+
+func ReadJournal(dir string) ([]JournalRecord, error) {
+	f, err := os.Open(filepath.Join(dir, JournalFile))
+	if err != nil {
+		return nil, errors.Wrapf(err, "[journal] failed to open journal in %s", dir)
+	}
+	defer f.Close()
+
+	records := make([]JournalRecord, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record JournalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, errors.Wrapf(err, "[journal] failed to parse line %d", len(records)+1)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "[journal] failed to read journal in %s", dir)
+	}
+	return records, nil
+}
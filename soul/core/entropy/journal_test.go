@@ -0,0 +1,200 @@
+package entropy
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordingSource пишет в журнал всё, что выдаёт source (как делает Lightmaster с включённым Recorder)
+type recordingSource struct {
+	source   EntropySource
+	recorder *Recorder
+}
+
+func (s *recordingSource) StartEntropyReading(ctx context.Context) error { return nil }
+
+func (s *recordingSource) GetEntropy(ctx context.Context) (Sample, error) {
+	sample, err := s.source.GetEntropy(ctx)
+	if err != nil {
+		return Sample{}, err
+	}
+	return sample, s.recorder.RecordUsage(JournalKindEntropy, sample)
+}
+
+func (s *recordingSource) GetChoice(ctx context.Context) (Sample, error) {
+	sample, err := s.source.GetChoice(ctx)
+	if err != nil {
+		return Sample{}, err
+	}
+	return sample, s.recorder.RecordUsage(JournalKindChoice, sample)
+}
+
+func TestJournalRecord(t *testing.T) {
+	recorder, err := NewRecorder(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frameTime := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	state := model.EntropyState{}
+	state.Entropy.Uint64, state.Choice.Uint64 = 11, 22
+	state.RawEntropy.Uint64, state.RawChoice.Uint64 = 33, 44
+	if err := recorder.RecordFrame(image.NewGray(image.Rect(0, 0, 4, 4)), state, true, frameTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.RecordUsage(JournalKindChoice, Sample{Value: 22, FrameTime: frameTime}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadJournal(recorder.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	frame := records[0]
+	if frame.Kind != JournalKindFrame || !frame.Computed || !frame.Timestamp.Equal(frameTime) ||
+		frame.Entropy != 11 || frame.Choice != 22 || frame.RawEntropy != 33 || frame.RawChoice != 44 {
+		t.Fatalf("unexpected frame record %+v", frame)
+	}
+	if _, err := os.Stat(filepath.Join(recorder.dir, frame.Frame)); err != nil {
+		t.Fatalf("frame file %s is not saved: %s", frame.Frame, err)
+	}
+	if choice := records[1]; choice.Kind != JournalKindChoice || choice.Value != 22 || !choice.FrameTime.Equal(frameTime) {
+		t.Fatalf("unexpected choice record %+v", choice)
+	}
+}
+
+// TestJournalReplay - сессия, проигранная из журнала, принимает те же решения, что и записанная
+func TestJournalReplay(t *testing.T) {
+	recorder, err := NewRecorder(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	recorded := NewEntropy(&recordingSource{NewPrngSource(42), recorder}, NewHealth(nil), false, nil)
+
+	var selected []uint
+	var entropy []uint64
+	for i := 0; i < 20; i++ {
+		// маленькие и огромные наборы, чтобы rejection sampling тоже отбрасывал значения
+		idx, err := recorded.Select(ctx, uint(i*i*7919+3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected = append(selected, idx)
+		sample, err := recorded.source.GetEntropy(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entropy = append(entropy, sample.Value)
+	}
+
+	source, err := NewJournalSource(recorder.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := NewEntropy(source, NewHealth(nil), false, nil)
+	for i := 0; i < 20; i++ {
+		idx, err := replayed.Select(ctx, uint(i*i*7919+3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if idx != selected[i] {
+			t.Fatalf("select %d: recorded %d, replayed %d", i, selected[i], idx)
+		}
+		sample, err := source.GetEntropy(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sample.Value != entropy[i] {
+			t.Fatalf("entropy %d: recorded %d, replayed %d", i, entropy[i], sample.Value)
+		}
+	}
+
+	if _, err := source.GetChoice(ctx); !errors.Is(err, ErrEntropyStarved) {
+		t.Fatalf("expected ErrEntropyStarved after end of journal, got %v", err)
+	}
+	if _, err := source.GetEntropy(ctx); !errors.Is(err, ErrEntropyStarved) {
+		t.Fatalf("expected ErrEntropyStarved after end of journal, got %v", err)
+	}
+}
+
+// TestRecorderRetention - старые кадры (и прошлых сессий) удаляются сверх maxSize, Close сбрасывает журнал
+func TestRecorderRetention(t *testing.T) {
+	baseDir := t.TempDir()
+	frame := image.NewGray(image.Rect(0, 0, 4, 4))
+	previous, err := NewRecorder(baseDir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.RecordFrame(frame, model.EntropyState{}, false, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := previous.RecordUsage(JournalKindChoice, Sample{}); err == nil {
+		t.Fatal("closed recorder must not write journal")
+	}
+	frameSize := previous.framesSize
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(previous.frames[0].path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// новая сессия в той же директории, помещаются только два кадра
+	recorder, err := NewRecorder(baseDir, frameSize*2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := recorder.RecordFrame(frame, model.EntropyState{}, false, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(baseDir, "*", "frame-*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || len(recorder.frames) != 2 || recorder.framesSize != frameSize*2 {
+		t.Fatalf("expected 2 newest frames, got %v", files)
+	}
+	if _, err := os.Stat(previous.frames[0].path); !os.IsNotExist(err) {
+		t.Fatalf("frame of previous session must be removed first: %v", err)
+	}
+	if records, err := ReadJournal(recorder.dir); err != nil || len(records) != 3 {
+		t.Fatalf("journal keeps all 3 frames: %d (err %v)", len(records), err)
+	}
+
+	// кадры старше maxAge удаляются при старте
+	aged, err := NewRecorder(baseDir, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aged.Close()
+	if len(aged.frames) != 2 {
+		t.Fatalf("fresh frames must be kept, got %d", len(aged.frames))
+	}
+	for _, f := range recorder.frames {
+		if err := os.Chtimes(f.path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if aged, err = NewRecorder(baseDir, 0, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	defer aged.Close()
+	if len(aged.frames) != 0 {
+		t.Fatalf("old frames must be removed, got %d", len(aged.frames))
+	}
+}
@@ -2,16 +2,16 @@ package entropy
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"github.com/artchitector/artchitect/model"
-	"github.com/artchitector/artchitect/soul/resources"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"image"
 	"image/color"
 	"math"
 	"math/bits"
-	"sync"
 	"time"
 )
 
@@ -20,116 +20,86 @@ const (
 	ImageNoise   = "noise"
 	ImageEntropy = "entropy"
 	ImageChoice  = "choice"
-
-	LastFramesToUse = 2 // Шум считается между двумя или более кадров
-	SquareSize      = 64 * 7
-	ResultSize      = 8
 )
 
+// frameStream - поставщик кадров для Lightmaster (веб-камера или директория с записанными кадрами)
+type frameStream interface {
+	GetStream(ctx context.Context) chan image.Image
+}
+
 /*
 Lightmaster отслеживает состояние энтропии в текущем кадре
 Еще он передаёт детализацию обработки энтропии на gate-сервер через redis. Это нужно, чтобы на клиенте был виден
 постоянный процесс обработки энтропии в виде jpeg-стримов, и видно было как картинка превращается в решение.
 
 Не каждое состояние используется в принятии решений, многие пропускаются.
+Геометрия обработки (регионы, размеры, число кадров) задаётся PipelineConfig.
 */
 type Lightmaster struct {
-	webcam        *resources.Webcam
+	frames        frameStream
 	gatekeeper    *Gatekeeper
-	lastNFrames   []image.Image
+	recorder      *Recorder // nil, если запись сессии выключена
+	health        *Health
+	config        PipelineConfig
+	lastNFrames   [][]image.Image // последние кадры каждого региона
+	lastDigest    []byte          // предыдущий результат conditioning, участвует в следующем
 	tags          []string
 	selectedWords map[string]int
 	counter       int
 
-	entropyMutex sync.Mutex
-
-	lastEntropyValueUsed bool
-	lastEntropyValue     float64
-	lastChoiceValueUsed  bool
-	lastChoiceValue      float64
+	entropyQueue *valueQueue
+	choiceQueue  *valueQueue
 }
 
-func NewLightmaster(webcam *resources.Webcam, gatekeeper *Gatekeeper) *Lightmaster {
+func NewLightmaster(frames frameStream, gatekeeper *Gatekeeper, recorder *Recorder, health *Health, config PipelineConfig) *Lightmaster {
+	lastNFrames := make([][]image.Image, len(config.Regions))
+	for idx := range lastNFrames {
+		lastNFrames[idx] = make([]image.Image, 0, config.FramesToUse)
+	}
 	return &Lightmaster{
-		webcam,
+		frames,
 		gatekeeper,
-		make([]image.Image, 0, LastFramesToUse),
+		recorder,
+		health,
+		config,
+		lastNFrames,
+		nil,
 		nil,
 		make(map[string]int),
 		0,
 
-		sync.Mutex{},
-
-		false,
-		-1.0,
-		false,
-		-1.0,
+		newValueQueue(QueueCapacity),
+		newValueQueue(QueueCapacity),
 	}
 }
 
-func (l *Lightmaster) GetEntropy(ctx context.Context) float64 {
-	if l.lastEntropyValue >= 0.0 && !l.lastEntropyValueUsed {
-		l.entropyMutex.Lock()
-		defer l.entropyMutex.Unlock()
-
-		l.lastEntropyValueUsed = true
-		return l.lastEntropyValue
-	}
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msgf("[lightmaster] stop reading entropy, while ctx.Done")
-			return 0.0
-		case <-time.After(time.Second * 5):
-			log.Error().Msgf("[lightmaster] too slow entropy get")
-			return 0.0
-		case <-time.Tick(time.Millisecond * 50):
-			if l.lastEntropyValue >= 0.0 && !l.lastEntropyValueUsed {
-				l.entropyMutex.Lock()
-				defer l.entropyMutex.Unlock()
-
-				l.lastEntropyValueUsed = true
-				return l.lastEntropyValue
-			}
-		}
+func (l *Lightmaster) GetEntropy(ctx context.Context) (Sample, error) {
+	sample, err := l.entropyQueue.Pop(ctx, QueueWaitTimeout)
+	if err != nil {
+		return Sample{}, errors.Wrap(err, "[lightmaster] failed to get entropy")
 	}
+	l.recordUsage(JournalKindEntropy, sample)
+	return sample, nil
 }
 
-func (l *Lightmaster) GetChoice(ctx context.Context) float64 {
-	if l.lastChoiceValue >= 0.0 && !l.lastChoiceValueUsed {
-		l.entropyMutex.Lock()
-		defer l.entropyMutex.Unlock()
-
-		l.lastChoiceValueUsed = true
-		return l.lastChoiceValue
+func (l *Lightmaster) GetChoice(ctx context.Context) (Sample, error) {
+	sample, err := l.choiceQueue.Pop(ctx, QueueWaitTimeout)
+	if err != nil {
+		return Sample{}, errors.Wrap(err, "[lightmaster] failed to get choice")
 	}
+	l.recordUsage(JournalKindChoice, sample)
+	return sample, nil
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msgf("[lightmaster] stop reading entropy, while ctx.Done")
-			return 0.0
-		case <-time.After(time.Second * 5):
-			log.Error().Msgf("[lightmaster] too slow entropy get")
-			return 0.0
-		case <-time.Tick(time.Millisecond * 50):
-			if l.lastChoiceValue >= 0.0 && !l.lastChoiceValueUsed {
-				l.entropyMutex.Lock()
-				defer l.entropyMutex.Unlock()
-
-				l.lastChoiceValueUsed = true
-				return l.lastChoiceValue
-			}
-		}
-	}
+func (l *Lightmaster) QueueStats() (entropy QueueStats, choice QueueStats) {
+	return l.entropyQueue.Stats(), l.choiceQueue.Stats()
 }
 
 /*
-Запускается процесс считывания кадров с веб-камеры и превращение их в float64-число
+Запускается процесс считывания кадров (с веб-камеры или из директории) и превращение их в uint64-число
 */
 func (l *Lightmaster) StartEntropyReading(ctx context.Context) error {
-	ch := l.webcam.GetStream(ctx)
+	ch := l.frames.GetStream(ctx)
 	for {
 		select {
 		case <-ctx.Done():
@@ -143,6 +113,7 @@ func (l *Lightmaster) StartEntropyReading(ctx context.Context) error {
 }
 
 func (l *Lightmaster) handleSingleFrame(ctx context.Context, newFrame image.Image) error {
+	frameTime := time.Now()
 	// step 1. source frame here
 	state := model.EntropyState{
 		IsShort:       false,
@@ -155,32 +126,47 @@ func (l *Lightmaster) handleSingleFrame(ctx context.Context, newFrame image.Imag
 	borderedFrame := l.addBordersOnFrame(newFrame)
 	state.Images["source"] = borderedFrame
 
-	// step 2. extract square image from source
-	square, err := l.extractSquare(newFrame)
-	if err != nil {
-		return errors.Wrap(err, "[lightmaster] failed extract square")
-	}
-
-	if len(l.lastNFrames) < LastFramesToUse {
-		l.lastNFrames = append(l.lastNFrames, square)
-	} else {
-		l.lastNFrames = append(l.lastNFrames[1:], square)
+	// step 2. extract square images (regions) from source
+	for idx, region := range l.config.Regions {
+		square, err := l.extractSquare(newFrame, region)
+		if err != nil {
+			return errors.Wrapf(err, "[lightmaster] failed extract square of region %d", idx)
+		}
+		if len(l.lastNFrames[idx]) < l.config.FramesToUse {
+			l.lastNFrames[idx] = append(l.lastNFrames[idx], square)
+		} else {
+			l.lastNFrames[idx] = append(l.lastNFrames[idx][1:], square)
+		}
 	}
 
-	if len(l.lastNFrames) == LastFramesToUse {
+	computed := len(l.lastNFrames[0]) == l.config.FramesToUse
+	if computed {
 		if err := l.pipelineEntropy(ctx, &state); err != nil {
 			return errors.Wrap(err, "[lightmaster] failed to pipeline entropy")
-		} else {
-			l.entropyMutex.Lock()
-			defer l.entropyMutex.Unlock()
+		}
+		// статистические тесты имеют смысл только на сырых битах, после хеша любой поток выглядит случайным
+		l.health.Add(ctx, state.RawEntropy.Uint64, state.RawChoice.Uint64)
+	}
 
-			l.lastEntropyValue = state.Entropy.Float64
-			l.lastEntropyValueUsed = false
-			l.lastChoiceValue = state.Choice.Float64
-			l.lastChoiceValueUsed = false
+	// кадр пишется в журнал до того, как его значения станут доступны потребителям.
+	// Пишется кадр целиком, потому что регионы могут быть в любом месте кадра
+	if l.recorder != nil {
+		if err := l.recorder.RecordFrame(newFrame, state, computed, frameTime); err != nil {
+			log.Error().Err(err).Msgf("[lightmaster] failed to record frame")
 		}
 	}
 
+	if computed {
+		l.entropyQueue.Push(Sample{state.Entropy.Uint64, frameTime})
+		l.choiceQueue.Push(Sample{state.Choice.Uint64, frameTime})
+	}
+
+	l.counter += 1
+	if l.counter%QueueStatsLogEvery == 0 {
+		entropyStats, choiceStats := l.QueueStats()
+		log.Info().Msgf("[lightmaster] queues: entropy %+v, choice %+v", entropyStats, choiceStats)
+	}
+
 	if err := l.gatekeeper.NotifyEntropyState(ctx, state); err != nil {
 		log.Error().Err(err).Msgf("[lightmaster] failed NotifyEntropyState")
 	}
@@ -188,85 +174,133 @@ func (l *Lightmaster) handleSingleFrame(ctx context.Context, newFrame image.Imag
 	return nil
 }
 
-func (l *Lightmaster) extractSquare(frame image.Image) (image.Image, error) {
-	oldBounds := frame.Bounds()
-	if oldBounds.Dx() < SquareSize || oldBounds.Dy() < SquareSize {
-		return nil, errors.Errorf("[lightmaster] too small image. size is %d and %d", oldBounds.Dx(), oldBounds.Dy())
+func (l *Lightmaster) recordUsage(kind string, sample Sample) {
+	if l.recorder == nil {
+		return
+	}
+	if err := l.recorder.RecordUsage(kind, sample); err != nil {
+		log.Error().Err(err).Msgf("[lightmaster] failed to record %s usage", kind)
 	}
-	squareRect := image.Rect(0, 0, SquareSize, SquareSize)
-	squareImg := image.NewRGBA(squareRect)
+}
 
-	leftOffset := (oldBounds.Dx() - squareRect.Dx()) / 2
-	topOffset := (oldBounds.Dy() - squareRect.Dy()) / 2
+// regionRect - квадрат региона в кадре. Центр квадрата задан в долях кадра, квадрат не выходит за границы кадра
+func (l *Lightmaster) regionRect(bounds image.Rectangle, region RegionConfig) (image.Rectangle, error) {
+	size := l.config.SquareSize
+	if bounds.Dx() < size || bounds.Dy() < size {
+		return image.Rectangle{}, errors.Errorf("[lightmaster] too small image. size is %d and %d", bounds.Dx(), bounds.Dy())
+	}
+	left := int(region.X*float64(bounds.Dx())) - size/2
+	top := int(region.Y*float64(bounds.Dy())) - size/2
+	left = clamp(left, 0, bounds.Dx()-size)
+	top = clamp(top, 0, bounds.Dy()-size)
+	return image.Rect(left, top, left+size, top+size).Add(bounds.Min), nil
+}
+
+func (l *Lightmaster) extractSquare(frame image.Image, region RegionConfig) (image.Image, error) {
+	rect, err := l.regionRect(frame.Bounds(), region)
+	if err != nil {
+		return nil, err
+	}
+	squareImg := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
 
-	for x := 0; x < SquareSize; x++ {
-		for y := 0; y < SquareSize; y++ {
-			squareImg.Set(x, y, frame.At(x+leftOffset, y+topOffset))
+	for x := 0; x < rect.Dx(); x++ {
+		for y := 0; y < rect.Dy(); y++ {
+			squareImg.Set(x, y, frame.At(x+rect.Min.X, y+rect.Min.Y))
 		}
 	}
 
 	return squareImg, nil
 }
 
+/*
+pipelineEntropy считает сырые значения для каждого региона, объединяет их (config.Combine)
+и пропускает через conditioning (config.Conditioning).
+На клиенте (jpeg-стримы) показываются картинки первого региона.
+*/
 func (l *Lightmaster) pipelineEntropy(ctx context.Context, state *model.EntropyState) error {
-	noiseImage, err := l.sourceToNoise()
+	entropies := make([]uint64, 0, len(l.lastNFrames))
+	choices := make([]uint64, 0, len(l.lastNFrames))
+	conditioner := sha256.New()
+	conditioner.Write(l.lastDigest)
+
+	for idx, frames := range l.lastNFrames {
+		noiseImage, deltas, err := l.sourceToNoise(frames)
+		if err != nil {
+			return errors.Wrapf(err, "[lightmaster] failed sourceToNoise for region %d", idx)
+		}
 
-	if err != nil {
-		return errors.Wrapf(err, "[lightmaster] failed sourceToNoise")
-	} else {
-		state.Images[ImageNoise] = noiseImage
-	}
+		entropyImage, entropyVal, err := l.noiseToEntropy(noiseImage)
+		if err != nil {
+			return errors.Wrapf(err, "[lightmaster] failed noiseToEntropy for region %d", idx)
+		}
 
-	entropyImage, entropyVal, err := l.noiseToEntropy(noiseImage)
-	if err != nil {
-		return errors.Wrapf(err, "[lightmaster] failed noiseToEntropy")
-	} else {
-		state.Images[ImageEntropy] = entropyImage
+		choiceImage, choiceVal, err := l.invertEntropy(entropyImage)
+		if err != nil {
+			return errors.Wrapf(err, "[lightmaster] failed invertEntropy for region %d", idx)
+		}
+
+		if idx == 0 {
+			state.Images[ImageNoise] = noiseImage
+			state.Images[ImageEntropy] = entropyImage
+			state.Images[ImageChoice] = choiceImage
+		}
+		entropies = append(entropies, entropyVal)
+		choices = append(choices, choiceVal)
+		conditioner.Write(deltas)
 	}
-	state.Entropy = l.makeEntropyStruct(entropyVal)
 
-	choiceImage, choiceVal, err := l.invertEntropy(entropyImage)
-	if err != nil {
-		return errors.Wrapf(err, "[lightmaster] failed invertEntropy")
-	} else {
-		state.Images[ImageChoice] = choiceImage
+	rawEntropy := l.config.combine(entropies)
+	rawChoice := l.config.combine(choices)
+	state.RawEntropy = l.makeEntropyStruct(rawEntropy)
+	state.RawChoice = l.makeEntropyStruct(rawChoice)
+	if l.config.Conditioning == ConditioningNone {
+		state.Entropy = state.RawEntropy
+		state.Choice = state.RawChoice
+		return nil
 	}
-	state.Choice = l.makeEntropyStruct(choiceVal)
+
+	raw := make([]byte, 16)
+	binary.BigEndian.PutUint64(raw[:8], rawEntropy)
+	binary.BigEndian.PutUint64(raw[8:], rawChoice)
+	conditioner.Write(raw)
+	l.lastDigest = conditioner.Sum(nil)
+	// 32 байта хеша: первые 8 - entropy, следующие 8 - choice
+	state.Entropy = l.makeEntropyStruct(binary.BigEndian.Uint64(l.lastDigest[:8]))
+	state.Choice = l.makeEntropyStruct(binary.BigEndian.Uint64(l.lastDigest[8:16]))
 
 	return nil
 }
 
-func (l *Lightmaster) sourceToNoise() (image.Image, error) {
-	lastFrame := l.lastNFrames[0] // take most distant frame as A, and current frame as B. B-A=noise
-	newFrame := l.lastNFrames[LastFramesToUse-1]
-	bounds := newFrame.Bounds()
+/*
+sourceToNoise - шум региона это сумма модулей разниц соседних кадров (при двух кадрах: B-A=noise).
+Кроме картинки шума возвращаются сами разницы (по 2 байта на канал), они идут в conditioning.
+*/
+func (l *Lightmaster) sourceToNoise(frames []image.Image) (image.Image, []byte, error) {
+	bounds := frames[len(frames)-1].Bounds()
 	noiseImage := image.NewRGBA(bounds)
+	deltas := make([]byte, 0, bounds.Dx()*bounds.Dy()*3*2)
 
-	for x := 0; x <= bounds.Dx(); x++ {
-		for y := 0; y <= bounds.Dy(); y++ {
-			oldColor := lastFrame.At(x, y)
-			newColor := newFrame.At(x, y)
-			if _, ok := oldColor.(color.RGBA); !ok {
-				return nil, errors.New("[lightmaster] old is not RGBA color")
-			}
-			if _, ok := newColor.(color.RGBA); !ok {
-				return nil, errors.New("[lightmaster] new is not RGBA color")
-			}
+	for x := 0; x < bounds.Dx(); x++ {
+		for y := 0; y < bounds.Dy(); y++ {
 			var newR, newG, newB int16
-			newR = newR + int16(newColor.(color.RGBA).R) - int16(oldColor.(color.RGBA).R)
-			if newR < 0 {
-				newR *= -1
-			}
-			newG = newG + int16(newColor.(color.RGBA).G) - int16(oldColor.(color.RGBA).G)
-			if newG < 0 {
-				newG *= -1
-			}
-			newB = newB + int16(newColor.(color.RGBA).B) - int16(oldColor.(color.RGBA).B)
-			if newB < 0 {
-				newB *= -1
+			for i := 1; i < len(frames); i++ {
+				oldColor, ok := frames[i-1].At(x, y).(color.RGBA)
+				if !ok {
+					return nil, nil, errors.New("[lightmaster] old is not RGBA color")
+				}
+				newColor, ok := frames[i].At(x, y).(color.RGBA)
+				if !ok {
+					return nil, nil, errors.New("[lightmaster] new is not RGBA color")
+				}
+				newR += abs16(int16(newColor.R) - int16(oldColor.R))
+				newG += abs16(int16(newColor.G) - int16(oldColor.G))
+				newB += abs16(int16(newColor.B) - int16(oldColor.B))
 			}
+			deltas = binary.BigEndian.AppendUint16(deltas, uint16(newR))
+			deltas = binary.BigEndian.AppendUint16(deltas, uint16(newG))
+			deltas = binary.BigEndian.AppendUint16(deltas, uint16(newB))
 
-			var amplifierRatio int16 = 30 // чем больше, чем меньше цвета будет на картине
+			amplifierRatio := l.config.AmplifierRatio // чем больше, чем меньше цвета будет на картине
 			// ВАЖНО! Усиление и изменение цветов на шумовой картине не влияет на результат. Дальше шум проходит нормализацию,
 			// Абсолютные значение не так важны, всё строится на относительной светимости пикселей.
 			// Цвет можно выбирать по своему вкусу и дизайну
@@ -279,25 +313,26 @@ func (l *Lightmaster) sourceToNoise() (image.Image, error) {
 			noiseImage.SetRGBA(x, y, noiseColor)
 		}
 	}
-	return noiseImage, nil
+	return noiseImage, deltas, nil
 }
 
 func (l *Lightmaster) noiseToEntropy(noiseImage image.Image) (image.Image, uint64, error) {
 	noiseBounds := noiseImage.Bounds()
-	resultBounds := image.Rect(0, 0, ResultSize, ResultSize)
+	resultSize := l.config.ResultSize
+	resultBounds := image.Rect(0, 0, resultSize, resultSize)
 	resultImg := image.NewRGBA(resultBounds)
 
 	proportion := noiseBounds.Dx() / resultBounds.Dx()
 	var minPower int64 = math.MaxInt64
 	var maxPower int64 = math.MinInt64
-	powers := make([][]int64, 0, 8)
+	powers := make([][]int64, 0, resultSize)
 
-	// collect powers of 64-pixels
+	// collect powers of resultSize*resultSize pixels
 	for x := 0; x < resultBounds.Dx(); x++ {
-		powers = append(powers, make([]int64, 8))
+		powers = append(powers, make([]int64, resultSize))
 		for y := 0; y < resultBounds.Dy(); y++ {
 			var powerOfPixel int64
-			// Проходим по всем пикселям в квадрате 56х56 и собираем их силу в сумму
+			// Проходим по всем пикселям в квадрате proportion x proportion (56х56 по умолчанию) и собираем их силу в сумму
 			for nx := x * proportion; nx < x*proportion+proportion; nx++ {
 				for ny := y * proportion; ny < y*proportion+proportion; ny++ {
 					clr := noiseImage.At(nx, ny)
@@ -327,8 +362,9 @@ func (l *Lightmaster) noiseToEntropy(noiseImage image.Image) (image.Image, uint6
 			resultImg.SetRGBA(x, y, color.RGBA{R: uint8(redPower), G: uint8(0), B: uint8(0), A: 255})
 
 			if redPower >= 128 {
-				byteIndex := x*ResultSize + y
-				entropyAnswer = entropyAnswer | 1<<(63-byteIndex)
+				// если бит больше 64, они сворачиваются по XOR
+				byteIndex := (x*resultSize + y) % 64
+				entropyAnswer = entropyAnswer ^ 1<<(63-byteIndex)
 			}
 		}
 	}
@@ -351,8 +387,8 @@ func (l *Lightmaster) invertEntropy(noiseImage image.Image) (image.Image, uint64
 			if power >= 128 { // set white
 				bytesImage.Set(x, y, color.RGBA{R: power, G: 0, B: 0, A: 255})
 
-				byteIndex := x*ResultSize + y
-				choiceAnswer = choiceAnswer | 1<<(63-byteIndex)
+				byteIndex := (x*bounds.Dx() + y) % 64
+				choiceAnswer = choiceAnswer ^ 1<<(63-byteIndex)
 			} else {
 				bytesImage.Set(x, y, color.RGBA{R: power, G: 0, B: 0, A: 255})
 			}
@@ -364,28 +400,27 @@ func (l *Lightmaster) invertEntropy(noiseImage image.Image) (image.Image, uint64
 
 func (l *Lightmaster) addBordersOnFrame(frame image.Image) image.Image {
 	oldBounds := frame.Bounds()
-	if oldBounds.Dx() < SquareSize || oldBounds.Dy() < SquareSize {
-		log.Error().Err(errors.Errorf("[lightmaster] too small image. size is %d and %d", oldBounds.Dx(), oldBounds.Dy())).Send()
-		return frame
+	rects := make([]image.Rectangle, 0, len(l.config.Regions))
+	for _, region := range l.config.Regions {
+		rect, err := l.regionRect(oldBounds, region)
+		if err != nil {
+			log.Error().Err(err).Send()
+			return frame
+		}
+		rects = append(rects, rect)
 	}
-	squareRect := image.Rect(0, 0, SquareSize, SquareSize)
 	bordersImage := image.NewRGBA(oldBounds)
 
-	leftOffset := (oldBounds.Dx() - squareRect.Dx()) / 2
-	topOffset := (oldBounds.Dy() - squareRect.Dy()) / 2
-	rightOffset := oldBounds.Dx() - leftOffset
-	bottomOffset := oldBounds.Dy() - topOffset
-
-	// Рисуем квадрат на картинке (область вырезания)
-	for x := 0; x < oldBounds.Dx(); x++ {
-		for y := 0; y < oldBounds.Dy(); y++ {
-
-			if (x == leftOffset || x == rightOffset) && y >= topOffset && y <= bottomOffset {
-				bordersImage.Set(x, y, color.RGBA{R: 180, G: 0, B: 0, A: 255})
-			} else if (y == topOffset || y == bottomOffset) && x >= leftOffset && x <= rightOffset {
-				bordersImage.Set(x, y, color.RGBA{R: 180, G: 0, B: 0, A: 255})
-			} else {
-				bordersImage.Set(x, y, frame.At(x, y))
+	// Рисуем квадраты регионов на картинке (области вырезания)
+	for x := oldBounds.Min.X; x < oldBounds.Max.X; x++ {
+		for y := oldBounds.Min.Y; y < oldBounds.Max.Y; y++ {
+			bordersImage.Set(x, y, frame.At(x, y))
+			for _, rect := range rects {
+				onVertical := (x == rect.Min.X || x == rect.Max.X) && y >= rect.Min.Y && y <= rect.Max.Y
+				onHorizontal := (y == rect.Min.Y || y == rect.Max.Y) && x >= rect.Min.X && x <= rect.Max.X
+				if onVertical || onHorizontal {
+					bordersImage.Set(x, y, color.RGBA{R: 180, G: 0, B: 0, A: 255})
+				}
 			}
 		}
 	}
@@ -396,7 +431,24 @@ func (l *Lightmaster) addBordersOnFrame(frame image.Image) image.Image {
 func (l *Lightmaster) makeEntropyStruct(value uint64) model.EntropyValue {
 	return model.EntropyValue{
 		Uint64:  value,
-		Float64: float64(value) / float64(math.MaxUint64),
+		Float64: uint64ToFloat(value),
 		Binary:  fmt.Sprintf("%064b", value),
 	}
 }
+
+func clamp(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
+
+func abs16(value int16) int16 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+package entropy
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+const (
+	CombineXor  = "xor"  // значения регионов складываются по XOR
+	CombineHash = "hash" // значения регионов хешируются sha256, берутся первые 8 байт
+
+	ConditioningSha256 = "sha256" // итоговые значения - sha256 от всех разниц пикселей, сырых значений и прошлого результата
+	ConditioningNone   = "none"   // итоговые значения - сырые биты с картинок entropy/choice
+
+	DefaultSquareSize      = 64 * 7
+	DefaultResultSize      = 8
+	DefaultFramesToUse     = 2 // Шум считается между двумя или более кадров
+	DefaultAmplifierRatio  = 30
+	DefaultRegionPositionX = 0.5
+	DefaultRegionPositionY = 0.5
+)
+
+/*
+PipelineConfig - геометрия обработки кадра в Lightmaster. Позволяет подстроиться под камеру и разрешение без перекомпиляции.
+
+Из кадра вырезается один или несколько квадратов (регионов). Для каждого региона шум считается по последним FramesToUse кадрам
+(сумма модулей разниц соседних кадров), шум сжимается в сетку ResultSize x ResultSize бит. Если бит больше 64,
+они сворачиваются по XOR в 64. Значения регионов объединяются в сырое число способом Combine.
+
+Сырые биты соседних кадров сильно коррелируют, поэтому по умолчанию они проходят conditioning: итоговое значение
+это хеш всего шума (разниц пикселей всех регионов), сырых значений и предыдущего хеша.
+Картинки (noise, entropy, choice) остаются прежними и показывают сырые биты.
+*/
+type PipelineConfig struct {
+	SquareSize     int            `yaml:"square_size"`
+	ResultSize     int            `yaml:"result_size"`     // ResultSize*ResultSize должно делиться на 64
+	FramesToUse    int            `yaml:"frames_to_use"`   // сколько последних кадров участвуют в подсчёте шума
+	AmplifierRatio int16          `yaml:"amplifier_ratio"` // только для картинки шума, на результат не влияет
+	Combine        string         `yaml:"combine"`
+	Conditioning   string         `yaml:"conditioning"`
+	Regions        []RegionConfig `yaml:"regions"`
+}
+
+// RegionConfig - центр квадрата в долях кадра (0.5, 0.5 - центр кадра), так регионы не зависят от разрешения камеры
+type RegionConfig struct {
+	X float64 `yaml:"x"`
+	Y float64 `yaml:"y"`
+}
+
+// DefaultPipelineConfig - один квадрат 448x448 в центре кадра, два кадра, 8x8 бит, sha256-conditioning
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{
+		SquareSize:     DefaultSquareSize,
+		ResultSize:     DefaultResultSize,
+		FramesToUse:    DefaultFramesToUse,
+		AmplifierRatio: DefaultAmplifierRatio,
+		Combine:        CombineXor,
+		Conditioning:   ConditioningSha256,
+		Regions:        []RegionConfig{{DefaultRegionPositionX, DefaultRegionPositionY}},
+	}
+}
+
+// LoadPipelineConfig читает yaml-файл. Незаполненные поля берутся из DefaultPipelineConfig
+func LoadPipelineConfig(filename string) (PipelineConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return PipelineConfig{}, errors.Wrapf(err, "[pipeline] failed to read config %s", filename)
+	}
+	var config PipelineConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return PipelineConfig{}, errors.Wrapf(err, "[pipeline] failed to parse config %s", filename)
+	}
+
+	defaults := DefaultPipelineConfig()
+	if config.SquareSize == 0 {
+		config.SquareSize = defaults.SquareSize
+	}
+	if config.ResultSize == 0 {
+		config.ResultSize = defaults.ResultSize
+	}
+	if config.FramesToUse == 0 {
+		config.FramesToUse = defaults.FramesToUse
+	}
+	if config.AmplifierRatio == 0 {
+		config.AmplifierRatio = defaults.AmplifierRatio
+	}
+	if config.Combine == "" {
+		config.Combine = defaults.Combine
+	}
+	if config.Conditioning == "" {
+		config.Conditioning = defaults.Conditioning
+	}
+	if len(config.Regions) == 0 {
+		config.Regions = defaults.Regions
+	}
+
+	return config, errors.Wrapf(config.Validate(), "[pipeline] wrong config %s", filename)
+}
+
+func (c PipelineConfig) Validate() error {
+	if c.ResultSize <= 0 || (c.ResultSize*c.ResultSize)%64 != 0 {
+		return errors.Errorf("[pipeline] result_size^2 must be multiple of 64, got result_size=%d", c.ResultSize)
+	}
+	if c.SquareSize < c.ResultSize || c.SquareSize%c.ResultSize != 0 {
+		return errors.Errorf("[pipeline] square_size=%d must be multiple of result_size=%d", c.SquareSize, c.ResultSize)
+	}
+	if c.FramesToUse < 2 {
+		return errors.Errorf("[pipeline] frames_to_use must be 2 or more, got %d", c.FramesToUse)
+	}
+	if c.Combine != CombineXor && c.Combine != CombineHash {
+		return errors.Errorf("[pipeline] unknown combine %s", c.Combine)
+	}
+	if c.Conditioning != ConditioningSha256 && c.Conditioning != ConditioningNone {
+		return errors.Errorf("[pipeline] unknown conditioning %s", c.Conditioning)
+	}
+	if len(c.Regions) == 0 {
+		return errors.New("[pipeline] no regions")
+	}
+	for idx, region := range c.Regions {
+		if region.X < 0 || region.X > 1 || region.Y < 0 || region.Y > 1 {
+			return errors.Errorf("[pipeline] region %d position must be in [0,1], got (%f,%f)", idx, region.X, region.Y)
+		}
+	}
+	return nil
+}
+
+func (c PipelineConfig) combine(values []uint64) uint64 {
+	if c.Combine == CombineHash {
+		buf := make([]byte, 8*len(values))
+		for idx, value := range values {
+			binary.BigEndian.PutUint64(buf[idx*8:], value)
+		}
+		sum := sha256.Sum256(buf)
+		return binary.BigEndian.Uint64(sum[:8])
+	}
+
+	var result uint64
+	for _, value := range values {
+		result ^= value
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package entropy
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"image"
+	"image/color"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestShippedPipelineConfigs - все конфиги lightmaster из files загружаются (tags_*.yaml - словари speller-а)
+func TestShippedPipelineConfigs(t *testing.T) {
+	files, err := filepath.Glob("../../files/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := 0
+	for _, file := range files {
+		if strings.HasPrefix(filepath.Base(file), "tags_") {
+			continue
+		}
+		if _, err := LoadPipelineConfig(file); err != nil {
+			t.Errorf("failed to load %s: %s", file, err)
+		}
+		loaded += 1
+	}
+	if loaded == 0 {
+		t.Fatalf("no pipeline configs found in files")
+	}
+}
+
+func TestPipelineConfigValidate(t *testing.T) {
+	if err := DefaultPipelineConfig().Validate(); err != nil {
+		t.Fatalf("default config must be valid: %s", err)
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(c *PipelineConfig)
+	}{
+		{name: "result size is not 64 bits", modify: func(c *PipelineConfig) { c.ResultSize = 6 }},
+		{name: "square not divisible", modify: func(c *PipelineConfig) { c.SquareSize = 450 }},
+		{name: "single frame", modify: func(c *PipelineConfig) { c.FramesToUse = 1 }},
+		{name: "unknown combine", modify: func(c *PipelineConfig) { c.Combine = "sum" }},
+		{name: "region out of frame", modify: func(c *PipelineConfig) { c.Regions = []RegionConfig{{X: 1.5, Y: 0.5}} }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultPipelineConfig()
+			tc.modify(&config)
+			if err := config.Validate(); err == nil {
+				t.Fatalf("expected validation error")
+			}
+		})
+	}
+}
+
+func TestRegionRect(t *testing.T) {
+	l := &Lightmaster{config: DefaultPipelineConfig()}
+	bounds := image.Rect(0, 0, 1280, 720)
+
+	rect, err := l.regionRect(bounds, RegionConfig{X: 0.5, Y: 0.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rect != image.Rect(416, 136, 864, 584) {
+		t.Fatalf("expected centred square, got %v", rect)
+	}
+
+	// регион у края кадра прижимается к границе
+	rect, err = l.regionRect(bounds, RegionConfig{X: 1, Y: 0})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rect != image.Rect(832, 0, 1280, 448) {
+		t.Fatalf("expected square in top right corner, got %v", rect)
+	}
+
+	if _, err := l.regionRect(image.Rect(0, 0, 320, 240), RegionConfig{X: 0.5, Y: 0.5}); err == nil {
+		t.Fatalf("expected error on too small frame")
+	}
+}
+
+func TestPipelineConditioning(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	noiseFrame := func() image.Image {
+		frame := image.NewRGBA(image.Rect(0, 0, DefaultSquareSize, DefaultSquareSize))
+		for x := 0; x < DefaultSquareSize; x++ {
+			for y := 0; y < DefaultSquareSize; y++ {
+				frame.SetRGBA(x, y, color.RGBA{R: uint8(rnd.Intn(256)), G: uint8(rnd.Intn(256)), B: uint8(rnd.Intn(256)), A: 255})
+			}
+		}
+		return frame
+	}
+	frames := []image.Image{noiseFrame(), noiseFrame()}
+	compute := func(l *Lightmaster) model.EntropyState {
+		state := model.EntropyState{Images: make(map[string]image.Image)}
+		l.lastNFrames[0] = frames
+		if err := l.pipelineEntropy(context.Background(), &state); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return state
+	}
+
+	config := DefaultPipelineConfig()
+	config.Conditioning = ConditioningNone
+	raw := compute(NewLightmaster(nil, nil, nil, nil, config))
+	if raw.Entropy != raw.RawEntropy || raw.Choice != raw.RawChoice {
+		t.Fatalf("without conditioning values must be raw, got %+v", raw)
+	}
+
+	l := NewLightmaster(nil, nil, nil, nil, DefaultPipelineConfig())
+	first := compute(l)
+	if first.RawEntropy != raw.RawEntropy || first.Entropy == first.RawEntropy {
+		t.Fatalf("expected conditioned entropy over the same raw bits, got %+v", first)
+	}
+	// те же кадры, но в хеш входит предыдущий результат
+	second := compute(l)
+	if second.RawEntropy != first.RawEntropy || second.Entropy == first.Entropy || second.Choice == first.Choice {
+		t.Fatalf("expected chained conditioning to change output on the same frames")
+	}
+}
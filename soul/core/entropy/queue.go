@@ -0,0 +1,115 @@
+package entropy
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	QueueCapacity      = 64              // сколько свежих значений lightmaster держит про запас
+	QueueWaitTimeout   = time.Second * 5 // сколько потребитель ждёт значение, прежде чем сдаться
+	QueueStatsLogEvery = 100             // раз в сколько кадров писать в лог метрики очередей
+)
+
+var ErrEntropyStarved = errors.New("[entropy] entropy source starved")
+
+// QueueStats - метрики очереди значений (для наблюдения за back-pressure)
+type QueueStats struct {
+	Size     int
+	Capacity int
+	Waiting  int    // потребителей ждут значение прямо сейчас
+	Produced uint64 // всего значений положено
+	Consumed uint64 // всего значений выдано
+	Dropped  uint64 // выброшено старых значений из-за переполнения (никто не успел забрать)
+	Starved  uint64 // сколько раз потребитель не дождался значения
+}
+
+/*
+valueQueue - кольцевой буфер свежих значений. Каждое значение выдаётся ровно одному потребителю и никогда повторно.
+Если потребители не успевают, самые старые значения выбрасываются (остаются свежие).
+Если значений нет, потребитель блокируется до появления нового значения, отмены контекста или таймаута.
+*/
+type valueQueue struct {
+	mutex  sync.Mutex
+	values []Sample
+	head   int
+	size   int
+	signal chan struct{} // закрывается при появлении нового значения
+	stats  QueueStats
+}
+
+func newValueQueue(capacity int) *valueQueue {
+	return &valueQueue{
+		values: make([]Sample, capacity),
+		signal: make(chan struct{}),
+		stats:  QueueStats{Capacity: capacity},
+	}
+}
+
+func (q *valueQueue) Push(value Sample) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.size == len(q.values) {
+		// выбрасываем самое старое значение
+		q.head = (q.head + 1) % len(q.values)
+		q.size -= 1
+		q.stats.Dropped += 1
+	}
+	q.values[(q.head+q.size)%len(q.values)] = value
+	q.size += 1
+	q.stats.Produced += 1
+
+	close(q.signal)
+	q.signal = make(chan struct{})
+}
+
+func (q *valueQueue) Pop(ctx context.Context, timeout time.Duration) (Sample, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		q.mutex.Lock()
+		if q.size > 0 {
+			value := q.values[q.head]
+			q.head = (q.head + 1) % len(q.values)
+			q.size -= 1
+			q.stats.Consumed += 1
+			q.mutex.Unlock()
+			return value, nil
+		}
+		signal := q.signal
+		q.stats.Waiting += 1
+		q.mutex.Unlock()
+
+		select {
+		case <-signal:
+			q.release(false)
+		case <-ctx.Done():
+			q.release(false)
+			return Sample{}, errors.Wrap(ctx.Err(), "[queue] stop waiting entropy value")
+		case <-deadline.C:
+			q.release(true)
+			return Sample{}, errors.Wrapf(ErrEntropyStarved, "[queue] no entropy value for %s", timeout)
+		}
+	}
+}
+
+func (q *valueQueue) release(starved bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.stats.Waiting -= 1
+	if starved {
+		q.stats.Starved += 1
+	}
+}
+
+func (q *valueQueue) Stats() QueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	stats := q.stats
+	stats.Size = q.size
+	return stats
+}
@@ -0,0 +1,43 @@
+package entropy
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"testing"
+	"time"
+)
+
+func TestValueQueue(t *testing.T) {
+	q := newValueQueue(2)
+	q.Push(Sample{Value: 1})
+	q.Push(Sample{Value: 2})
+	q.Push(Sample{Value: 3}) // 1 выброшено, как самое старое
+
+	for _, expected := range []uint64{2, 3} {
+		sample, err := q.Pop(context.Background(), time.Millisecond)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if sample.Value != expected {
+			t.Fatalf("expected %d, got %d", expected, sample.Value)
+		}
+	}
+
+	if _, err := q.Pop(context.Background(), time.Millisecond*10); !errors.Is(err, ErrEntropyStarved) {
+		t.Fatalf("expected starvation error, got %v", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		q.Push(Sample{Value: 4})
+	}()
+	sample, err := q.Pop(context.Background(), time.Second)
+	if err != nil || sample.Value != 4 {
+		t.Fatalf("expected blocked consumer to get 4, got %d (%v)", sample.Value, err)
+	}
+
+	stats := q.Stats()
+	if stats.Produced != 4 || stats.Consumed != 3 || stats.Dropped != 1 || stats.Starved != 1 || stats.Waiting != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,85 @@
+package entropy
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/pkg/errors"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+const UrandomPath = "/dev/urandom"
+
+/*
+UrandomSource берёт энтропию из /dev/urandom. Нужен там, где нет камеры (staging).
+Картинок для gate не даёт, поэтому на сайте поток энтропии в этом режиме будет пустой.
+*/
+type UrandomSource struct {
+	path string
+}
+
+func NewUrandomSource() *UrandomSource {
+	return &UrandomSource{UrandomPath}
+}
+
+func (s *UrandomSource) StartEntropyReading(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (s *UrandomSource) GetEntropy(ctx context.Context) (Sample, error) {
+	return s.read()
+}
+
+func (s *UrandomSource) GetChoice(ctx context.Context) (Sample, error) {
+	return s.read()
+}
+
+func (s *UrandomSource) read() (Sample, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return Sample{}, errors.Wrapf(err, "[urandom] failed to open %s", s.path)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return Sample{}, errors.Wrapf(err, "[urandom] failed to read %s", s.path)
+	}
+	return Sample{binary.BigEndian.Uint64(buf), time.Now()}, nil
+}
+
+/*
+PrngSource - детерминированный генератор. С одинаковым seed всегда даёт одинаковую последовательность решений,
+поэтому подходит для тестов и локальной отладки. В продакшене не использовать.
+*/
+type PrngSource struct {
+	mutex sync.Mutex
+	rnd   *rand.Rand
+}
+
+func NewPrngSource(seed int64) *PrngSource {
+	return &PrngSource{sync.Mutex{}, rand.New(rand.NewSource(seed))}
+}
+
+func (s *PrngSource) StartEntropyReading(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (s *PrngSource) GetEntropy(ctx context.Context) (Sample, error) {
+	return Sample{s.next(), time.Now()}, nil
+}
+
+func (s *PrngSource) GetChoice(ctx context.Context) (Sample, error) {
+	return Sample{s.next(), time.Now()}, nil
+}
+
+func (s *PrngSource) next() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.rnd.Uint64()
+}
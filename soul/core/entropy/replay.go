@@ -0,0 +1,74 @@
+package entropy
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"sync"
+)
+
+/*
+JournalSource проигрывает записанную Recorder-ом сессию: отдаёт ровно те значения entropy/choice и в том же порядке,
+в котором их получали потребители во время записи. Поэтому вся сессия творения (версия, seed, теги, победители лотереи)
+повторяется бит-в-бит.
+
+Сами кадры сессии можно прогнать через lightmaster отдельно (ENTROPY_SOURCE=frames с директорией сессии),
+чтобы увидеть, как из шума получились эти значения.
+*/
+type JournalSource struct {
+	mutex    sync.Mutex
+	entropy  []Sample
+	choices  []Sample
+	entropyI int
+	choiceI  int
+}
+
+func NewJournalSource(dir string) (*JournalSource, error) {
+	records, err := ReadJournal(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[replay] failed to load journal")
+	}
+	s := &JournalSource{}
+	frames := 0
+	for _, record := range records {
+		switch record.Kind {
+		case JournalKindFrame:
+			frames += 1
+		case JournalKindEntropy:
+			s.entropy = append(s.entropy, Sample{record.Value, record.FrameTime})
+		case JournalKindChoice:
+			s.choices = append(s.choices, Sample{record.Value, record.FrameTime})
+		}
+	}
+	log.Info().Msgf("[replay] loaded journal %s: frames=%d, entropy=%d, choices=%d", dir, frames, len(s.entropy), len(s.choices))
+	return s, nil
+}
+
+func (s *JournalSource) StartEntropyReading(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+func (s *JournalSource) GetEntropy(ctx context.Context) (Sample, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.entropyI >= len(s.entropy) {
+		return Sample{}, errors.Wrapf(ErrEntropyStarved, "[replay] journal is over, no more entropy values (total %d)", len(s.entropy))
+	}
+	value := s.entropy[s.entropyI]
+	s.entropyI += 1
+	return value, nil
+}
+
+func (s *JournalSource) GetChoice(ctx context.Context) (Sample, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.choiceI >= len(s.choices) {
+		return Sample{}, errors.Wrapf(ErrEntropyStarved, "[replay] journal is over, no more choice values (total %d)", len(s.choices))
+	}
+	value := s.choices[s.choiceI]
+	s.choiceI += 1
+	return value, nil
+}
@@ -3,6 +3,7 @@ package gifter
 import (
 	"context"
 	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -71,7 +72,7 @@ func (g *Gifter) sendCard(ctx context.Context) error {
 }
 
 func (g *Gifter) getArt(ctx context.Context) (uint, error) {
-	art, err := g.artsRepository.GetOriginSelectedArt(ctx)
+	art, err := g.artsRepository.GetOriginSelectedArt(entropy.WithPurpose(ctx, model.DecisionPurposeGift))
 	if err != nil && errors.Is(err, model.ErrArtsEmpty) {
 		return 0, nil
 	} else if err != nil {
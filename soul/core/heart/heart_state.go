@@ -3,6 +3,7 @@ package heart
 import (
 	"context"
 	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -83,7 +84,7 @@ func (hs *HeartState) work(ctx context.Context) error {
 }
 
 func (hs *HeartState) replace(ctx context.Context, index int) error {
-	if newCard, err := hs.cardGiver.GetOriginSelectedArt(ctx); err != nil {
+	if newCard, err := hs.cardGiver.GetOriginSelectedArt(entropy.WithPurpose(ctx, model.DecisionPurposeHeartDream)); err != nil {
 		return errors.Wrapf(err, "[heart_state] failed to get new card number")
 	} else {
 		log.Info().Msgf("[heart_state] selected new image #%d into index %d", newCard.ID, index)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -90,7 +91,7 @@ func (lr *Runner) startLottery(ctx context.Context, lottery model.Lottery) (mode
 	lottery.Started = time.Now()
 
 	// select from 10 to 100 winners
-	totalWinners, err := lr.entropy.Select(ctx, 90)
+	totalWinners, err := lr.entropy.Select(entropy2.WithPurpose(ctx, model.DecisionPurposeLotteryTotalWinners), 90)
 	if err != nil {
 		return model.Lottery{}, errors.Wrapf(err, "[runner] failed to get total winners from entropy (lottery=%d)", lottery.ID)
 	}
@@ -120,7 +121,7 @@ func (lr *Runner) performLotteryStep(ctx context.Context, lottery model.Lottery)
 	}
 
 	totalCards := uint(len(cards))
-	selection, err := lr.entropy.Select(ctx, totalCards)
+	selection, err := lr.entropy.Select(entropy2.WithPurpose(ctx, model.DecisionPurposeLotteryWinner), totalCards)
 	if err != nil {
 		return model.Lottery{}, false, errors.Wrapf(err, "[runner] failed to select from entropy with max=%d", totalCards)
 	}
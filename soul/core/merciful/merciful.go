@@ -11,6 +11,11 @@ import (
 
 type creator interface {
 	CreateWithoutEnjoy(ctx context.Context) (model.Art, error)
+	CreateRemix(ctx context.Context, kind string, parent model.Art, other model.Art) (model.Art, error)
+}
+
+type artRepository interface {
+	GetArt(ctx context.Context, ID uint) (model.Art, error)
 }
 
 type prayRepository interface {
@@ -27,13 +32,14 @@ type notifier interface {
 // Merciful asnwer prays
 type Merciful struct {
 	prayRepository prayRepository
+	artRepository  artRepository
 	creator        creator
 	notifier       notifier
 	mutex          sync.Mutex
 }
 
-func NewMerciful(prayRepository prayRepository, creator creator, notifier notifier) *Merciful {
-	return &Merciful{prayRepository, creator, notifier, sync.Mutex{}}
+func NewMerciful(prayRepository prayRepository, artRepository artRepository, creator creator, notifier notifier) *Merciful {
+	return &Merciful{prayRepository, artRepository, creator, notifier, sync.Mutex{}}
 }
 
 func (m *Merciful) AnswerPray(ctx context.Context) (bool, error) {
@@ -53,7 +59,7 @@ func (m *Merciful) AnswerPray(ctx context.Context) (bool, error) {
 	if pray, err = m.prayRepository.SetPrayRunning(ctx, pray); err != nil {
 		return false, errors.Wrapf(err, "[merciful] failed to set pray running")
 	}
-	card, err := m.creator.CreateWithoutEnjoy(ctx)
+	card, err := m.answer(ctx, pray)
 	if err != nil {
 		return false, errors.Wrap(err, "[merciful] failed to get answer")
 	}
@@ -64,3 +70,28 @@ func (m *Merciful) AnswerPray(ctx context.Context) (bool, error) {
 	}
 	return true, nil
 }
+
+// answer creates new art, or remix of art, if pray targets a card
+func (m *Merciful) answer(ctx context.Context, pray model.Pray) (model.Art, error) {
+	if pray.CardID == 0 {
+		return m.creator.CreateWithoutEnjoy(ctx)
+	}
+	parent, err := m.artRepository.GetArt(ctx, pray.CardID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warn().Msgf("[merciful] pray %d targets unknown art %d, answer with new art", pray.ID, pray.CardID)
+		return m.creator.CreateWithoutEnjoy(ctx)
+	} else if err != nil {
+		return model.Art{}, errors.Wrapf(err, "[merciful] failed to get art %d", pray.CardID)
+	}
+	var other model.Art
+	if pray.Remix == model.RemixCrossover {
+		other, err = m.artRepository.GetArt(ctx, pray.CrossCardID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warn().Msgf("[merciful] pray %d targets unknown art %d for crossover, answer with new seed", pray.ID, pray.CrossCardID)
+			return m.creator.CreateRemix(ctx, model.RemixNewSeed, parent, model.Art{})
+		} else if err != nil {
+			return model.Art{}, errors.Wrapf(err, "[merciful] failed to get art %d", pray.CrossCardID)
+		}
+	}
+	return m.creator.CreateRemix(ctx, pray.Remix, parent, other)
+}
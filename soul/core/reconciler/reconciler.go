@@ -0,0 +1,195 @@
+package reconciler
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/memory"
+	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/resizer"
+	"github.com/artchitector/artchitect/soul/core/saver"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	ReconcileInterval = time.Minute * 5
+	StuckTimeout      = time.Minute * 10 // creation of art takes about a minute, art unchanged for longer is stuck
+	ReconcileBatch    = 50
+)
+
+// what reconciler did with stuck art
+const (
+	ActionPublished = "published"
+	ActionDeleted   = "deleted"
+	ActionSkipped   = "skipped" // storage or memory is not available, retry next time
+	ActionSpooled   = "spooled" // images are in local spool, uploader publishes art
+)
+
+type artRepository interface {
+	GetStuckArts(ctx context.Context, before time.Time, limit uint) ([]model.Art, error)
+	SetArtState(ctx context.Context, artID uint, state string) error
+	DeleteArt(ctx context.Context, artID uint) error
+}
+
+// imageSaver - saver service of storage (full-size images) and memory (F-size images)
+type imageSaver interface {
+	GetFullsize(ctx context.Context, artID uint) ([]byte, error)
+	SaveArt(ctx context.Context, artID uint, imageData []byte) error
+}
+
+type memoryReader interface {
+	DownloadImage(ctx context.Context, cardID uint, size string) ([]byte, error)
+}
+
+// spooler - uploader of local spool (spool.Uploader)
+type spooler interface {
+	Spooled(artID uint) bool
+}
+
+type notifier interface {
+	NotifyNewCard(ctx context.Context, card model.Art) error
+}
+
+// Result - one stuck art and what was done with it
+type Result struct {
+	ArtID  uint
+	State  string
+	Action string
+	Err    error
+}
+
+/*
+Reconciler finishes arts, which creation was interrupted (soul crashed, storage or memory failed):
+  - painted: if full-size image reached storage, art goes on as stored, otherwise art is deleted (image is lost)
+  - stored: F-size image is made from full-size image of storage and uploaded to memory (if memory has no it yet), art is published
+
+Arts with images in local spool are left to uploader (if spooler is set), painted arts are skipped with KeepPainted.
+Published art is announced as new card (if notifier is set).
+*/
+type Reconciler struct {
+	artRepository artRepository
+	saver         imageSaver
+	memory        memoryReader
+	spooler       spooler
+	notifier      notifier
+	keepPainted   bool
+}
+
+func NewReconciler(artRepository artRepository, saver imageSaver, memory memoryReader, spooler spooler, notifier notifier) *Reconciler {
+	return &Reconciler{artRepository, saver, memory, spooler, notifier, false}
+}
+
+// KeepPainted - painted arts are not touched (arts_report -keep-painted)
+func (r *Reconciler) KeepPainted() {
+	r.keepPainted = true
+}
+
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Reconcile(ctx); err != nil {
+			log.Error().Err(err).Msgf("[reconciler] failed to reconcile arts")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile makes one pass over stuck arts
+func (r *Reconciler) Reconcile(ctx context.Context) ([]Result, error) {
+	arts, err := r.artRepository.GetStuckArts(ctx, time.Now().Add(-StuckTimeout), ReconcileBatch)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(arts))
+	for _, art := range arts {
+		action, err := r.reconcile(ctx, art)
+		results = append(results, Result{ArtID: art.ID, State: art.State, Action: action, Err: err})
+		if err != nil {
+			log.Error().Err(err).Msgf("[reconciler] art %d (%s): %s", art.ID, art.State, action)
+		} else {
+			log.Info().Msgf("[reconciler] art %d (%s): %s", art.ID, art.State, action)
+		}
+	}
+	return results, nil
+}
+
+func (r *Reconciler) reconcile(ctx context.Context, art model.Art) (string, error) {
+	if r.spooler != nil && r.spooler.Spooled(art.ID) {
+		return ActionSpooled, nil
+	}
+	if r.keepPainted && art.State == model.ArtStatePainted {
+		return ActionSkipped, nil
+	}
+	fullsize, err := r.saver.GetFullsize(ctx, art.ID)
+	if errors.Is(err, saver.ErrNotFound) {
+		if art.State == model.ArtStateStored {
+			// storage lost image, but maybe it is in memory already
+			if found, err := r.inMemory(ctx, art.ID); err != nil {
+				return ActionSkipped, err
+			} else if found {
+				return r.publish(ctx, art)
+			}
+		}
+		if err := r.artRepository.DeleteArt(ctx, art.ID); err != nil {
+			return ActionSkipped, errors.Wrap(err, "[reconciler] failed to delete art without image")
+		}
+		return ActionDeleted, nil
+	} else if err != nil {
+		return ActionSkipped, errors.Wrap(err, "[reconciler] failed to get image from storage")
+	}
+
+	if art.State == model.ArtStatePainted {
+		if err := r.artRepository.SetArtState(ctx, art.ID, model.ArtStateStored); err != nil {
+			return ActionSkipped, err
+		}
+	}
+
+	found, err := r.inMemory(ctx, art.ID)
+	if err != nil {
+		return ActionSkipped, err
+	}
+	if !found {
+		data, err := resizer.ResizeBytes(fullsize, model.SizeF)
+		if err != nil {
+			return ActionSkipped, errors.Wrap(err, "[reconciler] failed to resize image from storage")
+		}
+		if err := r.saver.SaveArt(ctx, art.ID, data); err != nil {
+			return ActionSkipped, errors.Wrap(err, "[reconciler] failed to upload art into memory")
+		}
+	}
+	return r.publish(ctx, art)
+}
+
+// inMemory checks that F-size image of art is in memory
+func (r *Reconciler) inMemory(ctx context.Context, artID uint) (bool, error) {
+	data, err := r.memory.DownloadImage(ctx, artID, model.SizeF)
+	if errors.Is(err, memory.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(err, "[reconciler] failed to check image in memory")
+	} else if len(data) == 0 {
+		return false, errors.New("[reconciler] memory answered with empty image") // memory is not OK
+	}
+	return true, nil
+}
+
+func (r *Reconciler) publish(ctx context.Context, art model.Art) (string, error) {
+	if err := r.artRepository.SetArtState(ctx, art.ID, model.ArtStatePublished); err != nil {
+		return ActionSkipped, err
+	}
+	art.State = model.ArtStatePublished
+	art.UploadedToStorage = true
+	art.UploadedToMemory = true
+	if r.notifier == nil {
+		return ActionPublished, nil // reconciler of arts_report command, without soul
+	}
+	if err := r.notifier.NotifyNewCard(ctx, art); err != nil {
+		log.Error().Err(err).Msgf("[reconciler] failed to notify new card %d", art.ID)
+	}
+	return ActionPublished, nil
+}
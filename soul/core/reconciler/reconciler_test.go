@@ -0,0 +1,159 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"github.com/artchitector/artchitect/memory"
+	"github.com/artchitector/artchitect/model"
+	"github.com/artchitector/artchitect/soul/core/saver"
+	"github.com/pkg/errors"
+	"image"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+type testRepository struct {
+	arts    []model.Art
+	states  map[uint]string
+	deleted []uint
+}
+
+func (r *testRepository) GetStuckArts(ctx context.Context, before time.Time, limit uint) ([]model.Art, error) {
+	return r.arts, nil
+}
+
+func (r *testRepository) SetArtState(ctx context.Context, artID uint, state string) error {
+	r.states[artID] = state
+	return nil
+}
+
+func (r *testRepository) DeleteArt(ctx context.Context, artID uint) error {
+	r.deleted = append(r.deleted, artID)
+	return nil
+}
+
+type testSaver struct {
+	fullsize map[uint][]byte
+	down     bool
+	uploaded []uint
+}
+
+func (s *testSaver) GetFullsize(ctx context.Context, artID uint) ([]byte, error) {
+	if s.down {
+		return nil, errors.New("connection refused")
+	}
+	data, found := s.fullsize[artID]
+	if !found {
+		return nil, saver.ErrNotFound
+	}
+	return data, nil
+}
+
+func (s *testSaver) SaveArt(ctx context.Context, artID uint, imageData []byte) error {
+	s.uploaded = append(s.uploaded, artID)
+	return nil
+}
+
+type testMemory struct {
+	images map[uint]bool
+}
+
+func (m *testMemory) DownloadImage(ctx context.Context, cardID uint, size string) ([]byte, error) {
+	if !m.images[cardID] {
+		return nil, memory.ErrNotFound
+	}
+	return []byte{1}, nil
+}
+
+type testSpooler map[uint]bool
+
+func (s testSpooler) Spooled(artID uint) bool {
+	return s[artID]
+}
+
+type testNotifier struct {
+	cards []uint
+}
+
+func (n *testNotifier) NotifyNewCard(ctx context.Context, card model.Art) error {
+	n.cards = append(n.cards, card.ID)
+	return nil
+}
+
+func testJPEG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 20, 30)), nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReconcile(t *testing.T) {
+	repo := &testRepository{
+		arts: []model.Art{
+			{ID: 1, State: model.ArtStatePainted}, // image is lost
+			{ID: 2, State: model.ArtStatePainted}, // crashed after storage upload
+			{ID: 3, State: model.ArtStateStored},  // memory upload failed
+			{ID: 4, State: model.ArtStateStored},  // crashed after memory upload
+		},
+		states: make(map[uint]string),
+	}
+	sav := &testSaver{fullsize: map[uint][]byte{2: testJPEG(t), 3: testJPEG(t), 4: testJPEG(t)}}
+	mem := &testMemory{images: map[uint]bool{4: true}}
+	ntf := &testNotifier{}
+
+	results, err := NewReconciler(repo, sav, mem, testSpooler{5: true}, ntf).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{ActionDeleted, ActionPublished, ActionPublished, ActionPublished, ActionSpooled}
+	for idx, result := range results {
+		if result.Action != expected[idx] || result.Err != nil {
+			t.Fatalf("art %d: expected %s, got %+v", result.ArtID, expected[idx], result)
+		}
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != 1 {
+		t.Fatalf("only art without image must be deleted: %v", repo.deleted)
+	}
+	if len(sav.uploaded) != 2 || sav.uploaded[0] != 2 || sav.uploaded[1] != 3 {
+		t.Fatalf("arts without memory image must be uploaded: %v", sav.uploaded)
+	}
+	if repo.states[2] != model.ArtStatePublished || repo.states[4] != model.ArtStatePublished || len(ntf.cards) != 3 {
+		t.Fatalf("unexpected states %v, notified %v", repo.states, ntf.cards)
+	}
+}
+
+func TestReconcileStorageDown(t *testing.T) {
+	repo := &testRepository{
+		arts:   []model.Art{{ID: 1, State: model.ArtStatePainted}},
+		states: make(map[uint]string),
+	}
+	results, err := NewReconciler(repo, &testSaver{down: true}, &testMemory{}, nil, nil).Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if results[0].Action != ActionSkipped || results[0].Err == nil || len(repo.deleted) != 0 {
+		t.Fatalf("art must be kept while storage is down: %+v", results[0])
+	}
+}
+
+func TestReconcileKeepPainted(t *testing.T) {
+	repo := &testRepository{
+		arts:   []model.Art{{ID: 1, State: model.ArtStatePainted}, {ID: 2, State: model.ArtStateStored}},
+		states: make(map[uint]string),
+	}
+	sav := &testSaver{fullsize: map[uint][]byte{2: testJPEG(t)}}
+	rcn := NewReconciler(repo, sav, &testMemory{images: map[uint]bool{}}, nil, nil)
+	rcn.KeepPainted()
+	results, err := rcn.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if results[0].Action != ActionSkipped || results[0].Err != nil || len(repo.deleted) != 0 {
+		t.Fatalf("painted art must be kept: %+v, deleted %v", results[0], repo.deleted)
+	}
+	if results[1].Action != ActionPublished || repo.states[2] != model.ArtStatePublished {
+		t.Fatalf("stored art must be published: %+v", results[1])
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"net/http"
 )
 
+var ErrNotFound = errors.New("[saver] not found")
+
 // Saver send binary image to saver-server, which lives in memory-server (near mother-database)
 type Saver struct {
 	memorySaverURL  string
@@ -58,7 +60,7 @@ func (s *Saver) SaveArt(ctx context.Context, artID uint, imageData []byte) error
 
 	// By now our original request body should have been populated, so let's just use it with our custom request
 	pth := fmt.Sprintf("%s/upload_art", s.memorySaverURL)
-	req, err := http.NewRequest("POST", pth, &requestBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pth, &requestBody)
 	if err != nil {
 		return errors.Wrapf(err, "[saver] failed art id=%d image saving", artID)
 	}
@@ -122,7 +124,7 @@ func (s *Saver) SaveUnity(ctx context.Context, filename string, imgFile []byte)
 
 	// By now our original request body should have been populated, so let's just use it with our custom request
 	pth := fmt.Sprintf("%s/upload_unity", s.memorySaverURL)
-	req, err := http.NewRequest("POST", pth, &requestBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pth, &requestBody)
 	if err != nil {
 		return errors.Wrapf(err, "[saver] failed %s image saving", filename)
 	}
@@ -178,7 +180,7 @@ func (s *Saver) SaveFullsize(ctx context.Context, artID uint, imageData []byte)
 
 	// By now our original request body should have been populated, so let's just use it with our custom request
 	pth := fmt.Sprintf("%s/upload_fullsize", s.storageSaverURL)
-	req, err := http.NewRequest("POST", pth, &requestBody)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pth, &requestBody)
 	if err != nil {
 		return errors.Wrapf(err, "[saver] failed art id=%d image saving", artID)
 	}
@@ -204,3 +206,25 @@ func (s *Saver) SaveFullsize(ctx context.Context, artID uint, imageData []byte)
 
 	return nil
 }
+
+// GetFullsize downloads full-size art back from storage (ErrNotFound if art was not uploaded)
+func (s *Saver) GetFullsize(ctx context.Context, artID uint) ([]byte, error) {
+	pth := fmt.Sprintf("%s/fullsize/%d", s.storageSaverURL, artID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pth, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[saver] failed to make request %s", pth)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[saver] failed to get fullsize art %d", artID)
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("[saver] failed to get fullsize art. URL: %s. Status: %d", pth, res.StatusCode)
+	}
+	data, err := io.ReadAll(res.Body)
+	return data, errors.Wrapf(err, "[saver] failed to read fullsize art %d", artID)
+}
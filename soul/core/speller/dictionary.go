@@ -0,0 +1,167 @@
+package speller
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"reflect"
+)
+
+const FlatCategory = "tags" // name of the single category of old flat dictionaries
+
+/*
+Dictionary - tags of one version, split into categories. Spell takes from every category from Min to Max tags
+(count is chosen by entropy), every tag is chosen by entropy proportionally to its weight.
+
+Categorized file:
+
+	categories:
+	  - name: subject
+	    min: 1
+	    max: 1
+	    tags:
+	      - Cathedral              # weight 1
+	      - {tag: Angel, weight: 3}
+	  - name: style
+	    min: 0
+	    max: 2
+	    tags: [...]
+
+Old flat file (list of strings) is loaded as single category "tags" with uniform weights and 1..MaxTags tags.
+Dictionary can have Rules (see rules.go).
+*/
+type Dictionary struct {
+	Categories []Category `yaml:"categories"`
+	Rules      Rules      `yaml:"rules"`
+	Params     Params     `yaml:"params"` // generation parameters (see params.go), flat dictionary uses defaults
+}
+
+type Category struct {
+	Name string        `yaml:"name"`
+	Min  uint          `yaml:"min"`
+	Max  uint          `yaml:"max"`
+	Tags []WeightedTag `yaml:"tags"`
+}
+
+type WeightedTag struct {
+	Tag    string `yaml:"tag"`
+	Weight uint   `yaml:"weight"`
+}
+
+// UnmarshalYAML - tag can be written as plain string (weight 1) or as {tag, weight}
+func (t *WeightedTag) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Tag = node.Value
+		t.Weight = 1
+		return nil
+	}
+	type plain WeightedTag
+	value := plain{Weight: 1}
+	if err := node.Decode(&value); err != nil {
+		return err
+	}
+	*t = WeightedTag(value)
+	return nil
+}
+
+func (c Category) labels() []string {
+	labels := make([]string, 0, len(c.Tags))
+	for _, tag := range c.Tags {
+		labels = append(labels, tag.Tag)
+	}
+	return labels
+}
+
+func (c Category) weights() []uint {
+	weights := make([]uint, 0, len(c.Tags))
+	for _, tag := range c.Tags {
+		weights = append(weights, tag.Weight)
+	}
+	return weights
+}
+
+// TotalTags - number of tags in all categories
+func (d Dictionary) TotalTags() int {
+	total := 0
+	for _, category := range d.Categories {
+		total += len(category.Tags)
+	}
+	return total
+}
+
+// LoadDictionary loads dictionary file (with sidecar rules) and validates it
+func LoadDictionary(filename string) (Dictionary, error) {
+	dictionary, err := parseDictionary(filename)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	return dictionary, errors.Wrapf(dictionary.validate(), "[speller] wrong dictionary %s", filename)
+}
+
+// parseDictionary loads dictionary without validation (linter reports all problems itself)
+func parseDictionary(filename string) (Dictionary, error) {
+	yamlFile, err := os.ReadFile(filename)
+	if err != nil {
+		return Dictionary{}, errors.Wrap(err, "failed to load yaml file")
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(yamlFile, &node); err != nil {
+		return Dictionary{}, errors.Wrap(err, "failed to parse yaml file")
+	}
+	if len(node.Content) == 0 {
+		return Dictionary{}, errors.Errorf("[speller] empty dictionary %s", filename)
+	}
+
+	var dictionary Dictionary
+	if node.Content[0].Kind == yaml.SequenceNode {
+		// old flat format
+		tags := []string{}
+		if err := node.Decode(&tags); err != nil {
+			return Dictionary{}, errors.Wrap(err, "failed to parse flat yaml file")
+		}
+		category := Category{Name: FlatCategory, Min: 1, Max: MaxTags}
+		for _, tag := range tags {
+			category.Tags = append(category.Tags, WeightedTag{tag, 1})
+		}
+		dictionary.Categories = []Category{category}
+	} else if err := node.Decode(&dictionary); err != nil {
+		return Dictionary{}, errors.Wrap(err, "failed to parse categorized yaml file")
+	}
+
+	rules, err := loadSidecarRules(filename)
+	if err != nil {
+		return Dictionary{}, err
+	}
+	if rules != nil {
+		if !reflect.DeepEqual(dictionary.Rules, Rules{}) {
+			return Dictionary{}, errors.Errorf("[speller] rules of %s are both inline and in sidecar file", filename)
+		}
+		dictionary.Rules = *rules
+	}
+	return dictionary, nil
+}
+
+func (d Dictionary) validate() error {
+	if len(d.Categories) == 0 {
+		return errors.New("[speller] no categories")
+	}
+	for _, category := range d.Categories {
+		if category.Min > category.Max {
+			return errors.Errorf("[speller] category %s: min=%d is greater than max=%d", category.Name, category.Min, category.Max)
+		}
+		if category.Max == 0 {
+			continue
+		}
+		var total uint
+		for _, tag := range category.Tags {
+			if tag.Tag == "" {
+				return errors.Errorf("[speller] category %s: empty tag", category.Name)
+			}
+			total += tag.Weight
+		}
+		if total == 0 {
+			return errors.Errorf("[speller] category %s: no tags with positive weight", category.Name)
+		}
+	}
+	return d.Rules.validate(d)
+}
@@ -0,0 +1,59 @@
+package speller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDictionary(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+		return filename
+	}
+
+	flat, err := LoadDictionary(write("flat.yaml", "- God\n- Allah\n- Cathedral\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(flat.Categories) != 1 || flat.Categories[0].Min != 1 || flat.Categories[0].Max != MaxTags {
+		t.Fatalf("flat file must be single uniform category, got %+v", flat.Categories)
+	}
+	if flat.TotalTags() != 3 || flat.Categories[0].Tags[2] != (WeightedTag{"Cathedral", 1}) {
+		t.Fatalf("unexpected flat tags %+v", flat.Categories[0].Tags)
+	}
+
+	categorized, err := LoadDictionary(write("categorized.yaml", `
+categories:
+  - name: subject
+    min: 1
+    max: 1
+    tags:
+      - Cathedral
+      - {tag: Angel, weight: 3}
+  - name: style
+    min: 0
+    max: 2
+    tags: [Baroque, {tag: Icon, weight: 0}]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(categorized.Categories) != 2 {
+		t.Fatalf("expected 2 categories, got %+v", categorized.Categories)
+	}
+	if weights := categorized.Categories[0].weights(); weights[0] != 1 || weights[1] != 3 {
+		t.Fatalf("unexpected weights %v", weights)
+	}
+	if categorized.Categories[1].Tags[1].Weight != 0 {
+		t.Fatalf("explicit zero weight must be kept")
+	}
+
+	if _, err := LoadDictionary(write("wrong.yaml", "categories:\n  - name: subject\n    min: 2\n    max: 1\n    tags: [A]\n")); err == nil {
+		t.Fatalf("expected error when min > max")
+	}
+}
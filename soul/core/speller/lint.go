@@ -0,0 +1,199 @@
+package speller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	LintError   = "ERROR"
+	LintWarning = "WARN"
+
+	MaxTagLength    = 80 // characters, longer tag is probably a broken line of yaml
+	MaxPromptTokens = 75 // CLIP limit is 77 tokens with start and end tokens, the rest of prompt is cut by engine
+)
+
+type LintIssue struct {
+	Level    string
+	Category string // empty - issue of whole dictionary
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	if i.Category == "" {
+		return fmt.Sprintf("%s %s", i.Level, i.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s", i.Level, i.Category, i.Message)
+}
+
+/*
+LintDictionary checks dictionary file: everything what fails LoadDictionary (errors) and what is suspicious (warnings) -
+duplicates, spaces, commas inside tags (Spell.Tags is comma string), overlong tags and prompt longer than CLIP limit.
+Released dictionary (it has version manifest, see ReleasedDictionaries) is not edited anymore, so its comma is a warning.
+*/
+func LintDictionary(filename string, released bool) (Dictionary, []LintIssue) {
+	dictionary, err := parseDictionary(filename)
+	if err != nil {
+		return Dictionary{}, []LintIssue{{LintError, "", err.Error()}}
+	}
+	issues := make([]LintIssue, 0)
+	add := func(level string, category string, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{level, category, fmt.Sprintf(format, args...)})
+	}
+	if err := dictionary.validate(); err != nil {
+		add(LintError, "", "%s", err)
+	}
+
+	categoryOf := make(map[string]string)
+	for _, category := range dictionary.Categories {
+		seen := make(map[string]bool, len(category.Tags))
+		for _, tag := range category.Tags {
+			switch {
+			case strings.TrimSpace(tag.Tag) == "":
+				add(LintError, category.Name, "empty tag")
+				continue
+			case strings.Contains(tag.Tag, ",") && released:
+				add(LintWarning, category.Name, "tag %q contains comma, it is split in spell (released, fix in next version)", tag.Tag)
+			case strings.Contains(tag.Tag, ","):
+				add(LintError, category.Name, "tag %q contains comma, it will be split in spell", tag.Tag)
+			case strings.TrimSpace(tag.Tag) != tag.Tag:
+				add(LintWarning, category.Name, "tag %q has leading or trailing spaces", tag.Tag)
+			}
+			if len(tag.Tag) > MaxTagLength {
+				add(LintWarning, category.Name, "tag %q is longer than %d characters", tag.Tag, MaxTagLength)
+			}
+			if tag.Weight == 0 {
+				add(LintWarning, category.Name, "tag %q has zero weight and is never taken", tag.Tag)
+			}
+			key := strings.ToLower(strings.TrimSpace(tag.Tag))
+			if seen[key] {
+				add(LintWarning, category.Name, "duplicate tag %q, its chance is higher", tag.Tag)
+			} else if other, found := categoryOf[key]; found {
+				add(LintWarning, category.Name, "tag %q is in category %s too", tag.Tag, other)
+			} else {
+				categoryOf[key] = category.Name
+			}
+			seen[key] = true
+		}
+	}
+
+	if tokens := dictionary.MaxPromptTokens(); tokens > MaxPromptTokens {
+		add(LintWarning, "", "longest possible prompt is about %d tokens, CLIP takes only %d", tokens, MaxPromptTokens)
+	}
+	return dictionary, issues
+}
+
+/*
+MaxPromptTokens - estimate of tokens of the longest prompt: Max longest tags from every category joined with commas.
+Companions and MaxPerCategory are not counted, so it is upper bound.
+*/
+func (d Dictionary) MaxPromptTokens() int {
+	total := 0
+	tags := 0
+	for _, category := range d.Categories {
+		lengths := make([]int, 0, len(category.Tags))
+		for _, tag := range category.Tags {
+			if tag.Weight > 0 {
+				lengths = append(lengths, EstimateTokens(tag.Tag))
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+		for i := 0; i < len(lengths) && uint(i) < category.Max; i++ {
+			total += lengths[i]
+			tags++
+		}
+	}
+	if tags > 1 {
+		total += tags - 1 // commas
+	}
+	return total
+}
+
+/*
+EstimateTokens - rough estimate of CLIP BPE tokens without vocabulary: every punctuation mark is a token,
+short word is one token, long word is split about every 5 letters.
+*/
+func EstimateTokens(text string) int {
+	tokens := 0
+	word := 0
+	flush := func() {
+		if word > 0 {
+			tokens += (word + 4) / 5
+			word = 0
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// DiffDictionaries describes changes from old to new dictionary (one line per change)
+func DiffDictionaries(old Dictionary, new Dictionary) []string {
+	diff := make([]string, 0)
+	oldCategories := make(map[string]Category, len(old.Categories))
+	for _, category := range old.Categories {
+		oldCategories[category.Name] = category
+	}
+	newCategories := make(map[string]bool, len(new.Categories))
+	for _, category := range new.Categories {
+		newCategories[category.Name] = true
+		previous, found := oldCategories[category.Name]
+		if !found {
+			diff = append(diff, fmt.Sprintf("+ category %s (%d tags, take %d-%d)", category.Name, len(category.Tags), category.Min, category.Max))
+			continue
+		}
+		if previous.Min != category.Min || previous.Max != category.Max {
+			diff = append(diff, fmt.Sprintf("~ category %s: take %d-%d -> %d-%d", category.Name, previous.Min, previous.Max, category.Min, category.Max))
+		}
+		diff = append(diff, diffTags(category.Name, previous.Tags, category.Tags)...)
+	}
+	for _, category := range old.Categories {
+		if !newCategories[category.Name] {
+			diff = append(diff, fmt.Sprintf("- category %s (%d tags)", category.Name, len(category.Tags)))
+		}
+	}
+	if !reflect.DeepEqual(old.Rules, new.Rules) {
+		diff = append(diff, fmt.Sprintf("~ rules: %+v -> %+v", old.Rules, new.Rules))
+	}
+	if !reflect.DeepEqual(old.Params, new.Params) {
+		diff = append(diff, fmt.Sprintf("~ params: %+v -> %+v", old.Params, new.Params))
+	}
+	return diff
+}
+
+func diffTags(category string, old []WeightedTag, new []WeightedTag) []string {
+	diff := make([]string, 0)
+	weights := make(map[string]uint, len(old))
+	for _, tag := range old {
+		weights[tag.Tag] = tag.Weight
+	}
+	present := make(map[string]bool, len(new))
+	for _, tag := range new {
+		present[tag.Tag] = true
+		weight, found := weights[tag.Tag]
+		if !found {
+			diff = append(diff, fmt.Sprintf("+ [%s] %s (weight %d)", category, tag.Tag, tag.Weight))
+		} else if weight != tag.Weight {
+			diff = append(diff, fmt.Sprintf("~ [%s] %s: weight %d -> %d", category, tag.Tag, weight, tag.Weight))
+		}
+	}
+	for _, tag := range old {
+		if !present[tag.Tag] {
+			diff = append(diff, fmt.Sprintf("- [%s] %s", category, tag.Tag))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,38 @@
+package speller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintDictionary(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "tags.yaml")
+	if err := os.WriteFile(filename, []byte("- God\n- stars,\n- God\n- ' Angel'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, issues := LintDictionary(filename, false)
+	levels := make(map[string]int)
+	for _, issue := range issues {
+		levels[issue.Level]++
+	}
+	if len(issues) != 3 || levels[LintError] != 1 || levels[LintWarning] != 2 {
+		t.Fatalf("expected comma error, duplicate and spaces warnings, got %v", issues)
+	}
+	// released dictionary is not edited, comma is a warning
+	if _, issues = LintDictionary(filename, true); len(issues) != 3 || issues[0].Level != LintWarning {
+		t.Fatalf("expected comma warning of released dictionary, got %v", issues)
+	}
+
+	if tokens := EstimateTokens("a cathedral, hyperrealistic"); tokens != 1+2+1+3 {
+		t.Fatalf("unexpected tokens estimate %d", tokens)
+	}
+}
+
+func TestDiffDictionaries(t *testing.T) {
+	old := Dictionary{Categories: []Category{{Name: "tags", Min: 1, Max: 2, Tags: []WeightedTag{{"A", 1}, {"B", 1}}}}}
+	new := Dictionary{Categories: []Category{{Name: "tags", Min: 1, Max: 3, Tags: []WeightedTag{{"A", 2}, {"C", 1}}}}}
+	if diff := DiffDictionaries(old, new); len(diff) != 4 {
+		t.Fatalf("expected take, weight, added and removed changes, got %v", diff)
+	}
+}
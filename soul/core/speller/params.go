@@ -0,0 +1,117 @@
+package speller
+
+import (
+	"context"
+	"fmt"
+	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DefaultWidth   = 640
+	DefaultHeight  = 960
+	DefaultSteps   = 50
+	DefaultUpscale = 4
+)
+
+/*
+Params - generation parameters of dictionary version (key "params" in categorized dictionary or in version manifest).
+Sampler, steps and CFG scale can be a single value or a list of variants, then the value is chosen by entropy.
+Empty sampler and zero CFG scale mean artist's defaults.
+
+	params:
+	  negative_prompt: blurry, text, watermark
+	  sampler: [k_euler_a, ddim]
+	  steps: 50
+	  cfg_scale: [7, 7.5, 9]
+	  width: 640
+	  height: 960
+	  upscale: 4
+*/
+type Params struct {
+	NegativePrompt string            `yaml:"negative_prompt"`
+	Sampler        Variants[string]  `yaml:"sampler"`
+	Steps          Variants[uint]    `yaml:"steps"`
+	CFGScale       Variants[float64] `yaml:"cfg_scale"`
+	Width          uint              `yaml:"width"`
+	Height         uint              `yaml:"height"`
+	Upscale        uint              `yaml:"upscale"`
+}
+
+// Variants - single value or list of values in yaml
+type Variants[T any] []T
+
+func (v *Variants[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value T
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		*v = Variants[T]{value}
+		return nil
+	}
+	var values []T
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+func (p Params) withDefaults() Params {
+	if len(p.Steps) == 0 {
+		p.Steps = Variants[uint]{DefaultSteps}
+	}
+	if p.Width == 0 {
+		p.Width = DefaultWidth
+	}
+	if p.Height == 0 {
+		p.Height = DefaultHeight
+	}
+	if p.Upscale == 0 {
+		p.Upscale = DefaultUpscale
+	}
+	return p
+}
+
+// selectParams fills generation parameters of spell. Parameters with several variants are chosen by entropy
+func (s *Speller) selectParams(ctx context.Context, params Params, spell *model.Spell) error {
+	params = params.withDefaults()
+	spell.NegativePrompt = params.NegativePrompt
+	spell.Width = params.Width
+	spell.Height = params.Height
+	spell.Upscale = params.Upscale
+
+	var err error
+	if spell.Sampler, err = selectVariant(ctx, s.entropy, model.DecisionPurposeSampler, params.Sampler); err != nil {
+		return errors.Wrap(err, "[speller] failed to select sampler")
+	}
+	if spell.Steps, err = selectVariant(ctx, s.entropy, model.DecisionPurposeSteps, params.Steps); err != nil {
+		return errors.Wrap(err, "[speller] failed to select steps")
+	}
+	if spell.CFGScale, err = selectVariant(ctx, s.entropy, model.DecisionPurposeCFGScale, params.CFGScale); err != nil {
+		return errors.Wrap(err, "[speller] failed to select cfg scale")
+	}
+	return nil
+}
+
+func selectVariant[T any](ctx context.Context, e entropy, purpose string, variants Variants[T]) (T, error) {
+	var value T
+	switch len(variants) {
+	case 0:
+		return value, nil
+	case 1:
+		return variants[0], nil
+	}
+	labels := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		labels = append(labels, fmt.Sprintf("%v", variant))
+	}
+	idx, err := e.Select(entropy2.WithLabels(entropy2.WithPurpose(ctx, purpose), labels), uint(len(variants)))
+	if err != nil {
+		return value, err
+	}
+	return variants[idx], nil
+}
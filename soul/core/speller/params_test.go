@@ -0,0 +1,36 @@
+package speller
+
+import (
+	"gopkg.in/yaml.v3"
+	"testing"
+)
+
+func TestParams(t *testing.T) {
+	var dictionary Dictionary
+	err := yaml.Unmarshal([]byte(`
+params:
+  negative_prompt: blurry, text
+  sampler: ddim
+  steps: [30, 50]
+  cfg_scale: [7, 7.5]
+  width: 512
+`), &dictionary)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	params := dictionary.Params.withDefaults()
+	if params.NegativePrompt != "blurry, text" || len(params.Sampler) != 1 || params.Sampler[0] != "ddim" {
+		t.Fatalf("unexpected params %+v", params)
+	}
+	if len(params.Steps) != 2 || params.Steps[1] != 50 || len(params.CFGScale) != 2 || params.CFGScale[1] != 7.5 {
+		t.Fatalf("lists must be loaded as variants, got %+v", params)
+	}
+	if params.Width != 512 || params.Height != DefaultHeight || params.Upscale != DefaultUpscale {
+		t.Fatalf("missing size must be default, got %+v", params)
+	}
+
+	defaults := Params{}.withDefaults()
+	if len(defaults.Steps) != 1 || defaults.Steps[0] != DefaultSteps || len(defaults.Sampler) != 0 {
+		t.Fatalf("unexpected defaults %+v", defaults)
+	}
+}
@@ -0,0 +1,300 @@
+package speller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const RegistryPollInterval = time.Second * 10 // how often manifest directory is checked for changes
+
+/*
+VersionManifest - one version of card generation, file <dir>/<anything>.yaml in manifest directory
+(dictionaries are kept outside of it, every yaml in directory is manifest):
+
+	id: v1.2
+	file: ../tags_v12.yaml    # dictionary, relative path is from manifest directory
+	model: sd-1.5             # model of artist engine (empty - artist default), backends translate it with models of ARTIST_BACKENDS
+	enabled: true             # disabled versions are loaded and validated, but not selected
+	weight: 1                 # chance of version among enabled ones
+	params:                   # generation params (see params.go) for dictionary without its own params (flat ones)
+	  negative_prompt: blurry, text
+	  sampler: [k_euler_a, ddim]
+*/
+type VersionManifest struct {
+	ID      string  `yaml:"id"`
+	File    string  `yaml:"file"`
+	Model   string  `yaml:"model"`
+	Enabled bool    `yaml:"enabled"`
+	Weight  uint    `yaml:"weight"`
+	Params  *Params `yaml:"params"`
+}
+
+// Version - manifest with loaded dictionary
+type Version struct {
+	VersionManifest
+	Dictionary Dictionary
+	Hash       string // sha256 of dictionary file and its sidecar rules (provenance of spells)
+}
+
+/*
+Registry keeps versions from manifest directory. It is reloaded on SIGHUP or when any file in directory
+(or any dictionary) is changed. Broken reload is reported and previous versions are kept.
+*/
+type Registry struct {
+	dir      string
+	mutex    sync.RWMutex
+	versions map[string]Version
+	enabled  []string // sorted enabled version ids
+	mtimes   map[string]time.Time
+}
+
+// NewRegistry loads versions from dir. Error is returned if versions are broken or no version is enabled
+func NewRegistry(dir string) (*Registry, error) {
+	r := &Registry{dir: dir}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads all manifests and dictionaries again. Versions are replaced only if everything is valid
+func (r *Registry) Reload() error {
+	versions, mtimes, err := loadVersions(r.dir)
+	if err != nil {
+		return err
+	}
+	enabled := make([]string, 0, len(versions))
+	for id, version := range versions {
+		if version.Enabled && version.Weight > 0 {
+			enabled = append(enabled, id)
+		}
+	}
+	if len(enabled) == 0 {
+		return errors.Errorf("[registry] no enabled versions in %s", r.dir)
+	}
+	sort.Strings(enabled)
+
+	r.mutex.Lock()
+	r.versions = versions
+	r.enabled = enabled
+	r.mtimes = mtimes
+	r.mutex.Unlock()
+
+	for _, id := range enabled {
+		version := versions[id]
+		log.Info().Msgf("[registry] version=%s enabled, file=%s, model=%s, weight=%d, tags=%d", id, version.File, version.Model, version.Weight, version.Dictionary.TotalTags())
+	}
+	return nil
+}
+
+// Watch reloads registry on SIGHUP and on file changes until ctx is done
+func (r *Registry) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(RegistryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Info().Msgf("[registry] SIGHUP, reload versions from %s", r.dir)
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			log.Info().Msgf("[registry] files changed, reload versions from %s", r.dir)
+		}
+		if err := r.Reload(); err != nil {
+			log.Error().Err(err).Msgf("[registry] failed to reload versions, previous versions are kept")
+		}
+	}
+}
+
+// Enabled returns enabled versions with weights for selection
+func (r *Registry) Enabled() ([]string, []uint) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	ids := make([]string, 0, len(r.enabled))
+	weights := make([]uint, 0, len(r.enabled))
+	for _, id := range r.enabled {
+		ids = append(ids, id)
+		weights = append(weights, r.versions[id].Weight)
+	}
+	return ids, weights
+}
+
+// Get returns version (enabled or not)
+func (r *Registry) Get(id string) (Version, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	version, found := r.versions[id]
+	if !found {
+		return Version{}, errors.Errorf("[registry] unknown version %s", id)
+	}
+	return version, nil
+}
+
+// changed - some file was added, removed or modified since last load
+func (r *Registry) changed() bool {
+	r.mutex.RLock()
+	known := r.mtimes
+	r.mutex.RUnlock()
+
+	files, err := manifestFiles(r.dir)
+	if err != nil {
+		log.Error().Err(err).Msgf("[registry] failed to list %s", r.dir)
+		return false
+	}
+	for _, file := range files {
+		if _, found := known[file]; !found {
+			return true // new manifest
+		}
+	}
+	for file, mtime := range known {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(mtime) {
+			return true // removed or modified manifest or dictionary
+		}
+	}
+	return false
+}
+
+func manifestFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadVersions loads and validates all manifests of dir with their dictionaries. Returns mtimes of all read files
+func loadVersions(dir string) (map[string]Version, map[string]time.Time, error) {
+	files, err := manifestFiles(dir)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "[registry] failed to list %s", dir)
+	}
+	if len(files) == 0 {
+		return nil, nil, errors.Errorf("[registry] no manifests in %s", dir)
+	}
+
+	versions := make(map[string]Version, len(files))
+	manifestOf := make(map[string]string, len(files))
+	mtimes := make(map[string]time.Time, len(files)*2)
+	for _, file := range files {
+		manifest, err := loadManifest(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if other, found := manifestOf[manifest.ID]; found {
+			return nil, nil, errors.Errorf("[registry] version %s is in manifests %s and %s", manifest.ID, other, file)
+		}
+		manifestOf[manifest.ID] = file
+		if !filepath.IsAbs(manifest.File) {
+			manifest.File = filepath.Join(dir, manifest.File)
+		}
+		dictionary, err := LoadDictionary(manifest.File)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "[registry] version %s", manifest.ID)
+		}
+		if manifest.Params != nil {
+			if !reflect.DeepEqual(dictionary.Params, Params{}) {
+				return nil, nil, errors.Errorf("[registry] version %s: params are both in manifest and in dictionary %s", manifest.ID, manifest.File)
+			}
+			dictionary.Params = *manifest.Params
+		}
+
+		dictionaryFiles := []string{manifest.File}
+		if rules := sidecarRulesFilename(manifest.File); fileExists(rules) {
+			dictionaryFiles = append(dictionaryFiles, rules)
+		}
+		hash, err := hashFiles(dictionaryFiles)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "[registry] version %s", manifest.ID)
+		}
+		versions[manifest.ID] = Version{manifest, dictionary, hash}
+
+		for _, f := range append([]string{file}, dictionaryFiles...) {
+			info, err := os.Stat(f)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "[registry] failed to stat %s", f)
+			}
+			mtimes[f] = info.ModTime()
+		}
+	}
+	return versions, mtimes, nil
+}
+
+func hashFiles(files []string) (string, error) {
+	hash := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read %s", file)
+		}
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fileExists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
+}
+
+// ReleasedDictionaries - absolute paths of dictionaries with version manifest in dir (enabled or not)
+func ReleasedDictionaries(dir string) (map[string]bool, error) {
+	files, err := manifestFiles(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[registry] failed to list %s", dir)
+	}
+	released := make(map[string]bool, len(files))
+	for _, file := range files {
+		manifest, err := loadManifest(file)
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(manifest.File) {
+			manifest.File = filepath.Join(dir, manifest.File)
+		}
+		path, err := filepath.Abs(manifest.File)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[registry] version %s", manifest.ID)
+		}
+		released[path] = true
+	}
+	return released, nil
+}
+
+func loadManifest(filename string) (VersionManifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return VersionManifest{}, errors.Wrapf(err, "[registry] failed to read manifest %s", filename)
+	}
+	manifest := VersionManifest{Enabled: true, Weight: 1}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return VersionManifest{}, errors.Wrapf(err, "[registry] failed to parse manifest %s", filename)
+	}
+	if manifest.ID == "" {
+		return VersionManifest{}, errors.Errorf("[registry] manifest %s: empty id", filename)
+	}
+	if manifest.File == "" {
+		return VersionManifest{}, errors.Errorf("[registry] manifest %s: empty file", filename)
+	}
+	return manifest, nil
+}
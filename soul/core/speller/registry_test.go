@@ -0,0 +1,101 @@
+package speller
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "versions"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write("tags_a.yaml", "- God\n- Cathedral\n")
+	write("tags_b.yaml", "- Angel\n")
+	write("versions/a.yaml", "id: a\nfile: ../tags_a.yaml\nmodel: sd-1.5\nweight: 3\n")
+	write("versions/b.yaml", "id: b\nfile: ../tags_b.yaml\nenabled: false\n")
+
+	registry, err := NewRegistry(filepath.Join(dir, "versions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ids, weights := registry.Enabled()
+	if len(ids) != 1 || ids[0] != "a" || weights[0] != 3 {
+		t.Fatalf("unexpected enabled versions %v %v", ids, weights)
+	}
+	if version, err := registry.Get("b"); err != nil || version.Dictionary.TotalTags() != 1 {
+		t.Fatalf("disabled version must be loaded, got %+v, %v", version, err)
+	}
+	if registry.changed() {
+		t.Fatalf("nothing changed yet")
+	}
+
+	// broken dictionary - previous versions are kept
+	write("tags_b.yaml", "categories: [{name: x, min: 2, max: 1, tags: [A]}]\n")
+	write("versions/c.yaml", "id: c\nfile: ../tags_a.yaml\n")
+	if !registry.changed() {
+		t.Fatalf("new manifest must be detected")
+	}
+	if err := registry.Reload(); err == nil {
+		t.Fatalf("expected error on broken dictionary")
+	}
+	if ids, _ := registry.Enabled(); len(ids) != 1 {
+		t.Fatalf("previous versions must be kept, got %v", ids)
+	}
+
+	write("tags_b.yaml", "- Angel\n")
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ids, _ := registry.Enabled(); len(ids) != 2 || ids[1] != "c" {
+		t.Fatalf("unexpected enabled versions after reload %v", ids)
+	}
+}
+
+func TestRegistryFiles(t *testing.T) {
+	registry, err := NewRegistry("../../files/versions")
+	if err != nil {
+		t.Fatalf("versions of soul/files are broken: %s", err)
+	}
+	if ids, _ := registry.Enabled(); len(ids) == 0 {
+		t.Fatalf("no enabled versions")
+	}
+	// v3 is disabled, but its categorized dictionary is loaded
+	if version, err := registry.Get("v3"); err != nil || len(version.Dictionary.Categories) < 2 {
+		t.Fatalf("categorized dictionary v3 is broken: %d categories (err %v)", len(version.Dictionary.Categories), err)
+	}
+}
+
+func TestRegistryManifestParams(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "versions"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write("tags_flat.yaml", "- God\n- Cathedral\n")
+	write("versions/flat.yaml", "id: flat\nfile: ../tags_flat.yaml\nparams:\n  negative_prompt: blurry\n  sampler: [k_euler_a, ddim]\n  cfg_scale: 9\n")
+	registry, err := NewRegistry(filepath.Join(dir, "versions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	version, _ := registry.Get("flat")
+	params := version.Dictionary.Params
+	if params.NegativePrompt != "blurry" || len(params.Sampler) != 2 || len(params.CFGScale) != 1 || params.CFGScale[0] != 9 {
+		t.Fatalf("params of manifest must be used for flat dictionary, got %+v", params)
+	}
+
+	write("tags_flat.yaml", "categories: [{name: x, min: 1, max: 1, tags: [A]}]\nparams:\n  steps: 30\n")
+	if err := registry.Reload(); err == nil {
+		t.Fatalf("expected error when params are both in manifest and dictionary")
+	}
+}
@@ -0,0 +1,174 @@
+package speller
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+/*
+MakeRemixSpell makes new Spell from spell of existing art:
+  - model.RemixNewSeed - same tags, new seed from entropy
+  - model.RemixSwapTag - same seed, one tag is swapped with other tag of its category (rules of dictionary are kept)
+  - model.RemixCrossover - tags of parent are cut at entropy point and joined with tail of other spell, new seed
+
+Generation parameters are taken from parent spell. other is used only for crossover.
+*/
+func (s *Speller) MakeRemixSpell(ctx context.Context, kind string, parent model.Spell, other model.Spell, state *model.CreationState) (model.Spell, error) {
+	spell := parent
+	spell.Model = gorm.Model{} // new spell
+	state.Version = spell.Version
+	s.notify(ctx, state)
+
+	tags := model.ParseTags(parent.Tags)
+	var err error
+	switch kind {
+	case model.RemixNewSeed:
+		err = s.selectSeed(ctx, &spell)
+	case model.RemixSwapTag:
+		tags, err = s.swapTag(ctx, parent.Version, tags)
+		if version, err := s.registry.Get(parent.Version); err == nil {
+			spell.DictionaryHash = version.Hash // new tag is from current dictionary
+		}
+	case model.RemixCrossover:
+		if tags, err = s.crossover(ctx, tags, model.ParseTags(other.Tags)); err == nil {
+			err = s.selectSeed(ctx, &spell)
+		}
+	default:
+		err = errors.Errorf("unknown remix kind %s", kind)
+	}
+	if err != nil {
+		return model.Spell{}, errors.Wrapf(err, "[speller] failed to remix spell %d", parent.ID)
+	}
+	spell.Tags = strings.Join(tags, ",")
+	state.Seed = spell.Seed
+	state.Tags = tags
+	state.TagsCount = uint(len(tags))
+	s.notify(ctx, state)
+
+	spell, err = s.spellRepository.Save(ctx, spell)
+	if err != nil {
+		return model.Spell{}, errors.Wrap(err, "[speller] failed to save spell in repository")
+	}
+	log.Info().Msgf("speller made %s remix of spell %d: %+v", kind, parent.ID, spell)
+	return spell, nil
+}
+
+// selectSeed sets new seed of spell with raw entropy value behind it
+func (s *Speller) selectSeed(ctx context.Context, spell *model.Spell) error {
+	var sample entropy2.Sample
+	seed, err := s.entropy.Select(entropy2.WithSample(entropy2.WithPurpose(ctx, model.DecisionPurposeSeed), &sample), model.MaxSeed)
+	if err != nil {
+		return errors.Wrap(err, "[speller] failed to get seed")
+	}
+	spell.Seed = seed
+	spell.SeedRaw = strconv.FormatUint(sample.Value, 10)
+	spell.SeedFrameTime = sample.FrameTime
+	return nil
+}
+
+// swapTag replaces one tag (chosen by entropy from tags known by dictionary) with other tag of its category
+func (s *Speller) swapTag(ctx context.Context, versionID string, tags []string) ([]string, error) {
+	version, err := s.registry.Get(versionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't swap tag of spell")
+	}
+	dictionary := version.Dictionary
+	categoryOf := make(map[string]int, dictionary.TotalTags())
+	for idx, category := range dictionary.Categories {
+		for _, tag := range category.Tags {
+			if _, found := categoryOf[tag.Tag]; !found {
+				categoryOf[tag.Tag] = idx
+			}
+		}
+	}
+	positions := make([]int, 0, len(tags))
+	labels := make([]string, 0, len(tags))
+	for idx, tag := range tags {
+		if _, found := categoryOf[tag]; found {
+			positions = append(positions, idx)
+			labels = append(labels, tag)
+		}
+	}
+	if len(positions) == 0 {
+		return nil, errors.Errorf("no tags of version %s in spell", versionID)
+	}
+	selected, err := s.entropy.Select(entropy2.WithLabels(entropy2.WithPurpose(ctx, model.DecisionPurposeRemixSwap), labels), uint(len(positions)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select swapped tag")
+	}
+	position := positions[selected]
+	old := tags[position]
+	category := dictionary.Categories[categoryOf[old]]
+
+	// kept tags are taken first, so new tag is checked by rules against them
+	spellTags := newSpellTags(dictionary)
+	for idx, tag := range tags {
+		if idx == position {
+			continue
+		}
+		categoryIdx, found := categoryOf[tag]
+		if !found {
+			spellTags.taken[tag] = true // tag of older dictionary is kept, but has no category for rules
+			continue
+		}
+		if _, conflict := spellTags.add(tag, dictionary.Categories[categoryIdx].Name); conflict != nil {
+			spellTags.taken[tag] = true // parent could be made with older rules
+		}
+	}
+	weights := category.weights()
+	var total uint
+	for idx, tag := range category.Tags {
+		if tag.Tag == old {
+			weights[idx] = 0
+		}
+		total += weights[idx]
+	}
+	if total == 0 {
+		return nil, errors.Errorf("no other tags in category %s to swap %s", category.Name, old)
+	}
+	added, err := s.drawTag(entropy2.WithLabels(entropy2.WithPurpose(ctx, model.DecisionPurposeTag), category.labels()), spellTags, category, weights)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return nil, errors.Errorf("no tag of category %s to swap %s without conflicts", category.Name, old)
+	}
+	log.Info().Msgf("[speller] swap tag %s with %v", old, added)
+
+	result := make([]string, 0, len(tags)+len(added)-1)
+	result = append(result, tags[:position]...)
+	result = append(result, added...)
+	return append(result, tags[position+1:]...), nil
+}
+
+// crossover takes head of first tags (at least one tag) and tail of second tags (at least one tag), duplicates are dropped
+func (s *Speller) crossover(ctx context.Context, first []string, second []string) ([]string, error) {
+	if len(first) == 0 || len(second) == 0 {
+		return nil, errors.New("crossover of spell without tags")
+	}
+	ctx = entropy2.WithPurpose(ctx, model.DecisionPurposeRemixCrossover)
+	head, err := s.entropy.Select(ctx, uint(len(first)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select cut point of first spell")
+	}
+	tail, err := s.entropy.Select(ctx, uint(len(second)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to select cut point of second spell")
+	}
+
+	result := make([]string, 0, len(first)+len(second))
+	taken := make(map[string]bool)
+	for _, tag := range append(append([]string{}, first[:head+1]...), second[tail:]...) {
+		if !taken[tag] {
+			taken[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,105 @@
+package speller
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sequenceEntropy returns given indexes one by one
+type sequenceEntropy struct {
+	indexes []uint
+}
+
+func (e *sequenceEntropy) next() uint {
+	idx := e.indexes[0]
+	e.indexes = e.indexes[1:]
+	return idx
+}
+
+func (e *sequenceEntropy) Select(ctx context.Context, totalVariants uint) (uint, error) {
+	return e.next() % totalVariants, nil
+}
+
+func (e *sequenceEntropy) SelectWeighted(ctx context.Context, weights []uint) (uint, error) {
+	return e.next(), nil
+}
+
+func TestRemixTags(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "versions"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tags.yaml"), []byte("- A\n- B\n- C\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "versions", "v.yaml"), []byte("id: v\nfile: ../tags.yaml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRegistry(filepath.Join(dir, "versions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// swap second tag (B): A is drawn first and conflicts, then C
+	s := NewSpeller(nil, &sequenceEntropy{[]uint{1, 0, 2}}, nil, registry)
+	tags, err := s.swapTag(context.Background(), "v", model.ParseTags("A, B,Unknown"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"A", "C", "Unknown"}) {
+		t.Fatalf("unexpected swapped tags %v", tags)
+	}
+
+	// head of first spell to index 1, tail of second from index 1, duplicates dropped
+	s = NewSpeller(nil, &sequenceEntropy{[]uint{1, 1}}, nil, registry)
+	tags, err = s.crossover(context.Background(), []string{"A", "B", "C"}, []string{"X", "B", "Y"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"A", "B", "Y"}) {
+		t.Fatalf("unexpected crossover tags %v", tags)
+	}
+}
+
+// TestSwapTagOutsideDictionary - kept tag of older dictionary is not charged to any category by rules
+func TestSwapTagOutsideDictionary(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "versions"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dictionary := `
+rules:
+  max_per_category: {subject: 1}
+categories:
+  - {name: subject, min: 1, max: 1, tags: [A, B, C]}
+  - {name: style, min: 0, max: 1, tags: [X, Y]}
+`
+	if err := os.WriteFile(filepath.Join(dir, "tags.yaml"), []byte(dictionary), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "versions", "v.yaml"), []byte("id: v\nfile: ../tags.yaml\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := NewRegistry(filepath.Join(dir, "versions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// swap A (first of known tags) with B, Old must not fill category subject
+	indexes := []uint{0}
+	for i := 0; i <= MaxRedraws; i++ {
+		indexes = append(indexes, 1)
+	}
+	s := NewSpeller(nil, &sequenceEntropy{indexes}, nil, registry)
+	tags, err := s.swapTag(context.Background(), "v", []string{"Old", "A", "X"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(tags, []string{"Old", "B", "X"}) {
+		t.Fatalf("unexpected swapped tags %v", tags)
+	}
+}
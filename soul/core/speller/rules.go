@@ -0,0 +1,168 @@
+package speller
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+const MaxRedraws = 10 // how many times tag is re-drawn on conflict, then tag place is skipped (if category has min tags)
+
+/*
+Rules - constraints on tags combination in one spell. Rules are checked while entropy selects tags:
+conflicting tag is re-drawn.
+
+Rules are written in dictionary file (key "rules") or in sidecar file <dictionary>.rules.yaml (for flat dictionaries):
+
+	allow_duplicates: false           # by default the same tag can't be taken twice
+	exclusive:                        # only one tag from every group
+	  - [black and white, vibrant colors]
+	companions:                       # tag is taken only together with its companions
+	  Angel: [wings]
+	max_per_category:                 # limit of tags from category, companions included
+	  style: 2
+*/
+type Rules struct {
+	AllowDuplicates bool                `yaml:"allow_duplicates"`
+	Exclusive       [][]string          `yaml:"exclusive"`
+	Companions      map[string][]string `yaml:"companions"`
+	MaxPerCategory  map[string]uint     `yaml:"max_per_category"`
+}
+
+// loadSidecarRules loads <name>.rules.yaml near dictionary file. Returns nil if there is no such file
+func loadSidecarRules(dictionaryFilename string) (*Rules, error) {
+	filename := sidecarRulesFilename(dictionaryFilename)
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "[speller] failed to read rules %s", filename)
+	}
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, errors.Wrapf(err, "[speller] failed to parse rules %s", filename)
+	}
+	return &rules, nil
+}
+
+func sidecarRulesFilename(dictionaryFilename string) string {
+	return strings.TrimSuffix(dictionaryFilename, ".yaml") + ".rules.yaml"
+}
+
+func (r Rules) validate(d Dictionary) error {
+	seen := make(map[string]int)
+	for idx, group := range r.Exclusive {
+		if len(group) < 2 {
+			return errors.Errorf("[speller] exclusive group %d must have 2 or more tags", idx)
+		}
+		for _, tag := range group {
+			if other, found := seen[tag]; found && other != idx {
+				return errors.Errorf("[speller] tag %s is in exclusive groups %d and %d", tag, other, idx)
+			}
+			seen[tag] = idx
+		}
+	}
+	categories := make(map[string]bool)
+	for _, category := range d.Categories {
+		categories[category.Name] = true
+	}
+	for name := range r.MaxPerCategory {
+		if !categories[name] {
+			return errors.Errorf("[speller] max_per_category for unknown category %s", name)
+		}
+	}
+	return nil
+}
+
+// spellTags - tags of the spell, which is being generated, and rules check
+type spellTags struct {
+	rules       Rules
+	categoryOf  map[string]string // tag -> category (to count companions)
+	groupOf     map[string]int    // tag -> exclusive group
+	tags        []string
+	taken       map[string]bool
+	perCategory map[string]uint
+	groups      map[int]string // exclusive group -> taken tag
+}
+
+func newSpellTags(dictionary Dictionary) *spellTags {
+	categoryOf := make(map[string]string, dictionary.TotalTags())
+	for _, category := range dictionary.Categories {
+		for _, tag := range category.Tags {
+			if _, found := categoryOf[tag.Tag]; !found {
+				categoryOf[tag.Tag] = category.Name
+			}
+		}
+	}
+	groupOf := make(map[string]int)
+	for idx, group := range dictionary.Rules.Exclusive {
+		for _, tag := range group {
+			groupOf[tag] = idx
+		}
+	}
+	return &spellTags{
+		dictionary.Rules,
+		categoryOf,
+		groupOf,
+		make([]string, 0),
+		make(map[string]bool),
+		make(map[string]uint),
+		make(map[int]string),
+	}
+}
+
+/*
+add takes tag from category with all its companions. If any of them breaks the rules, nothing is taken
+and conflict is returned (the tag should be re-drawn). Returns taken tags.
+*/
+func (s *spellTags) add(tag string, category string) ([]string, error) {
+	candidates := []string{tag}
+	candidateCategories := map[string]string{tag: category}
+	visited := map[string]bool{tag: true}
+	for i := 0; i < len(candidates); i++ {
+		for _, companion := range s.rules.Companions[candidates[i]] {
+			if visited[companion] || (s.taken[companion] && !s.rules.AllowDuplicates) {
+				continue // companion is already in spell
+			}
+			visited[companion] = true
+			candidates = append(candidates, companion)
+			candidateCategories[companion] = s.categoryOf[companion]
+		}
+	}
+
+	perCategory := make(map[string]uint)
+	groups := make(map[int]string)
+	for _, candidate := range candidates {
+		if s.taken[candidate] && !s.rules.AllowDuplicates {
+			return nil, errors.Errorf("duplicate tag %s", candidate)
+		}
+		if group, found := s.groupOf[candidate]; found {
+			other, taken := s.groups[group]
+			if !taken {
+				other, taken = groups[group]
+			}
+			if taken && other != candidate {
+				return nil, errors.Errorf("tag %s is exclusive with %s", candidate, other)
+			}
+			groups[group] = candidate
+		}
+		candidateCategory := candidateCategories[candidate]
+		perCategory[candidateCategory] += 1
+		if max, found := s.rules.MaxPerCategory[candidateCategory]; found && s.perCategory[candidateCategory]+perCategory[candidateCategory] > max {
+			return nil, errors.Errorf("tag %s exceeds max %d tags of category %s", candidate, max, candidateCategory)
+		}
+	}
+
+	for _, candidate := range candidates {
+		s.taken[candidate] = true
+		s.tags = append(s.tags, candidate)
+	}
+	for name, count := range perCategory {
+		s.perCategory[name] += count
+	}
+	for group, candidate := range groups {
+		s.groups[group] = candidate
+	}
+	return candidates, nil
+}
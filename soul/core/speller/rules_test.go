@@ -0,0 +1,151 @@
+package speller
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
+	"strings"
+	"testing"
+)
+
+func TestSpellTagsRules(t *testing.T) {
+	dictionary := Dictionary{
+		Categories: []Category{
+			{Name: "subject", Min: 1, Max: 3, Tags: []WeightedTag{{"Angel", 1}, {"Cathedral", 1}}},
+			{Name: "style", Min: 0, Max: 3, Tags: []WeightedTag{{"black and white", 1}, {"vibrant colors", 1}, {"wings", 1}, {"gold", 1}}},
+		},
+		Rules: Rules{
+			Exclusive:      [][]string{{"black and white", "vibrant colors"}},
+			Companions:     map[string][]string{"Angel": {"wings"}},
+			MaxPerCategory: map[string]uint{"style": 2},
+		},
+	}
+	if err := dictionary.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tags := newSpellTags(dictionary)
+	steps := []struct {
+		tag      string
+		category string
+		added    []string // nil - conflict
+	}{
+		{tag: "Angel", category: "subject", added: []string{"Angel", "wings"}},
+		{tag: "Angel", category: "subject"}, // duplicate
+		{tag: "wings", category: "style"},   // duplicate (came as companion)
+		{tag: "black and white", category: "style", added: []string{"black and white"}},
+		{tag: "vibrant colors", category: "style"}, // exclusive with black and white
+		{tag: "gold", category: "style"},           // style is full: wings + black and white
+		{tag: "Cathedral", category: "subject", added: []string{"Cathedral"}},
+	}
+	for _, step := range steps {
+		added, err := tags.add(step.tag, step.category)
+		if step.added == nil {
+			if err == nil {
+				t.Fatalf("expected conflict on %s, got %v", step.tag, added)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected conflict on %s: %s", step.tag, err)
+		}
+		if len(added) != len(step.added) {
+			t.Fatalf("expected %v, got %v", step.added, added)
+		}
+	}
+	expected := []string{"Angel", "wings", "black and white", "Cathedral"}
+	for idx, tag := range expected {
+		if tags.tags[idx] != tag {
+			t.Fatalf("expected %v, got %v", expected, tags.tags)
+		}
+	}
+}
+
+type valuesSource struct {
+	values []uint64
+}
+
+func (s *valuesSource) StartEntropyReading(ctx context.Context) error { return nil }
+func (s *valuesSource) GetEntropy(ctx context.Context) (entropy2.Sample, error) {
+	return s.GetChoice(ctx)
+}
+func (s *valuesSource) GetChoice(ctx context.Context) (entropy2.Sample, error) {
+	value := s.values[0]
+	s.values = s.values[1:]
+	return entropy2.Sample{Value: value}, nil
+}
+
+type testDecisionRepository struct {
+	decisions []model.Decision
+}
+
+func (r *testDecisionRepository) SaveDecision(ctx context.Context, decision model.Decision) (model.Decision, error) {
+	r.decisions = append(r.decisions, decision)
+	return decision, nil
+}
+
+// TestRedrawnTagDecision - tag re-drawn because of rules is saved in decisions as rejected
+func TestRedrawnTagDecision(t *testing.T) {
+	dictionary := Dictionary{Categories: []Category{
+		{Name: "subject", Min: 2, Max: 2, Tags: []WeightedTag{{"Angel", 1}, {"Cathedral", 3}}},
+	}}
+	repo := &testDecisionRepository{}
+	// seed, tags count, Angel (point 0), Angel again (point 4 % 4 = 0), Cathedral (point 1)
+	e := entropy2.NewEntropy(&valuesSource{[]uint64{10, 10, 0, 4, 1}}, entropy2.NewHealth(nil), false, repo)
+	spell, err := NewSpeller(nil, e, nil, nil).PreviewSpell(context.Background(), "v", dictionary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spell.Tags != "Angel,Cathedral" {
+		t.Fatalf("unexpected tags %s", spell.Tags)
+	}
+
+	tags := make([]model.Decision, 0)
+	for _, decision := range repo.decisions {
+		if decision.Purpose == model.DecisionPurposeTag {
+			tags = append(tags, decision)
+		}
+	}
+	expected := []struct {
+		label    string
+		point    uint
+		rejected bool
+	}{{"Angel", 0, false}, {"Angel", 0, true}, {"Cathedral", 1, false}}
+	if len(tags) != len(expected) {
+		t.Fatalf("expected %d tag decisions, got %+v", len(expected), tags)
+	}
+	for idx, decision := range tags {
+		if decision.Label != expected[idx].label || decision.Point != expected[idx].point ||
+			decision.Rejected != expected[idx].rejected || decision.Total != 4 {
+			t.Fatalf("decision %d: expected %+v, got %+v", idx, expected[idx], decision)
+		}
+	}
+}
+
+// TestImpossibleMin - category can't get min tags because of exclusive group, spell is not made with fewer tags
+func TestImpossibleMin(t *testing.T) {
+	dictionary := Dictionary{
+		Categories: []Category{
+			{Name: "subject", Min: 1, Max: 1, Tags: []WeightedTag{{"Angel", 1}, {"Cathedral", 1}}},
+			{Name: "style", Min: 2, Max: 2, Tags: []WeightedTag{{"black and white", 1}, {"vibrant colors", 1}}},
+		},
+		Rules: Rules{Exclusive: [][]string{{"black and white", "vibrant colors"}}},
+	}
+	e := entropy2.NewEntropy(entropy2.NewPrngSource(1), entropy2.NewHealth(nil), false, nil)
+	if _, err := NewSpeller(nil, e, nil, nil).PreviewSpell(context.Background(), "v", dictionary); err == nil {
+		t.Fatalf("expected error of category style with impossible min")
+	}
+
+	// style with max 2 and min 1 skips impossible place (when 2 places are planned), count of tags is real
+	dictionary.Categories[1].Min = 1
+	for i := 0; i < 10; i++ {
+		state := &model.CreationState{}
+		spell, err := NewSpeller(nil, e, nil, nil).spellOfVersion(context.Background(), Version{Dictionary: dictionary}, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tags := strings.Split(spell.Tags, ","); len(tags) != 2 || state.TagsCount != 2 {
+			t.Fatalf("expected 2 tags, got %v (tags count %d)", tags, state.TagsCount)
+		}
+	}
+}
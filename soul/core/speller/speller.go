@@ -3,10 +3,9 @@ package speller
 import (
 	"context"
 	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
-	"gopkg.in/yaml.v3"
-	"os"
 	"strings"
 )
 
@@ -22,6 +21,7 @@ type spellRepository interface {
 
 type entropy interface {
 	Select(ctx context.Context, totalVariants uint) (uint, error)
+	SelectWeighted(ctx context.Context, weights []uint) (uint, error)
 }
 
 /*
@@ -31,11 +31,11 @@ type Speller struct {
 	spellRepository spellRepository
 	entropy         entropy
 	notifier        notifier
-	dictionaries    map[string][]string
+	registry        *Registry
 }
 
-func NewSpeller(spellRepository spellRepository, entropy entropy, notifier notifier) *Speller {
-	return &Speller{spellRepository, entropy, notifier, make(map[string][]string)}
+func NewSpeller(spellRepository spellRepository, entropy entropy, notifier notifier, registry *Registry) *Speller {
+	return &Speller{spellRepository, entropy, notifier, registry}
 }
 
 func (s *Speller) MakeSpell(ctx context.Context, artistState *model.CreationState) (model.Spell, error) {
@@ -56,98 +56,125 @@ func (s *Speller) generateSpell(ctx context.Context, state *model.CreationState)
 	if err != nil {
 		return model.Spell{}, errors.Wrap(err, "[speller] failed select version")
 	}
-	state.Version = version
+	return s.spellOfVersion(ctx, version, state)
+}
+
+// PreviewSpell generates spell from dictionary without saving it (to check dictionary before release)
+func (s *Speller) PreviewSpell(ctx context.Context, versionID string, dictionary Dictionary) (model.Spell, error) {
+	version := Version{VersionManifest: VersionManifest{ID: versionID}, Dictionary: dictionary}
+	return s.spellOfVersion(ctx, version, &model.CreationState{})
+}
+
+func (s *Speller) spellOfVersion(ctx context.Context, version Version, state *model.CreationState) (model.Spell, error) {
+	state.Version = version.ID
 	s.notify(ctx, state)
-	selection, err := s.entropy.Select(ctx, model.MaxSeed)
-	if err != nil {
-		return model.Spell{}, errors.Wrap(err, "[speller] failed to get selection")
+	spell := model.Spell{
+		Version:        version.ID,
+		EngineModel:    version.Model,
+		DictionaryHash: version.Hash,
+	}
+	if err := s.selectSeed(ctx, &spell); err != nil {
+		return model.Spell{}, err
 	}
-	state.Seed = selection
+	state.Seed = spell.Seed
 	s.notify(ctx, state)
-	tags, err := s.generateTags(ctx, version, state)
+	tags, err := s.generateTags(ctx, version.Dictionary, state)
 	if err != nil {
 		return model.Spell{}, errors.Wrap(err, "[speller] failed generate tags")
 	}
-	return model.Spell{
-		Tags:    strings.Join(tags, ","),
-		Seed:    selection,
-		Version: version,
-	}, nil
-}
-
-func (s *Speller) generateTags(ctx context.Context, version string, state *model.CreationState) ([]string, error) {
-	dictionary, err := s.getDictionary(ctx, version)
-	if err != nil {
-		return []string{}, errors.Wrap(err, "failed to get Dictionary")
+	spell.Tags = strings.Join(tags, ",")
+	if err := s.selectParams(ctx, version.Dictionary.Params, &spell); err != nil {
+		return model.Spell{}, errors.Wrap(err, "[speller] failed to select generation params")
 	}
+	return spell, nil
+}
 
-	tagsToTake, err := s.entropy.Select(ctx, MaxTags)
-	tagsToTake += 1 // Select returns [0,MaxTags). Plus 1 is ok
+func (s *Speller) generateTags(ctx context.Context, dictionary Dictionary, state *model.CreationState) ([]string, error) {
 
-	tags := make([]string, 0, tagsToTake)
-	if err != nil {
-		return []string{}, errors.Wrap(err, "[speller][generateTags] failed get tagsToTake")
+	// first entropy decides how many tags to take from every category, then takes tags. State shows planned count first
+	counts := make([]uint, 0, len(dictionary.Categories))
+	for _, category := range dictionary.Categories {
+		count, err := s.entropy.Select(entropy2.WithPurpose(ctx, model.DecisionPurposeTagsCount), category.Max-category.Min+1)
+		if err != nil {
+			return []string{}, errors.Wrapf(err, "[speller][generateTags] failed get tags count for category %s", category.Name)
+		}
+		count += category.Min // Select returns [0,max-min]
+		counts = append(counts, count)
+		state.TagsCount += count
 	}
-	state.TagsCount = tagsToTake
 	s.notify(ctx, state)
 
-	allowedTagsLen := uint(len(dictionary))
-	for i := uint(0); i < tagsToTake; i++ {
-		tag, err := s.entropy.Select(ctx, allowedTagsLen)
-		if err != nil {
-			return []string{}, errors.Wrap(err, "[speller][generateTags] failed get tag number")
+	tags := newSpellTags(dictionary)
+	for idx, category := range dictionary.Categories {
+		tagCtx := entropy2.WithLabels(entropy2.WithPurpose(ctx, model.DecisionPurposeTag), category.labels())
+		weights := category.weights()
+		drawn := uint(0)
+		for i := uint(0); i < counts[idx]; i++ {
+			added, err := s.drawTag(tagCtx, tags, category, weights)
+			if err != nil {
+				return []string{}, err
+			}
+			if added == nil {
+				continue // place is skipped
+			}
+			drawn += 1
+			state.Tags = append(state.Tags, added...)
+			s.notify(ctx, state)
+		}
+		if drawn < category.Min {
+			return []string{}, errors.Errorf(
+				"[speller][generateTags] category %s got %d tags without conflicts, min is %d", category.Name, drawn, category.Min,
+			)
 		}
-		tags = append(tags, dictionary[tag])
-		state.Tags = append(state.Tags, dictionary[tag])
-		s.notify(ctx, state)
 	}
-	return tags, nil
+	// skipped places and companions change count of tags
+	state.TagsCount = uint(len(tags.tags))
+	s.notify(ctx, state)
+	return tags.tags, nil
 }
 
-func (s *Speller) getDictionary(ctx context.Context, version string) ([]string, error) {
-	dict, found := s.dictionaries[version]
-	if found {
-		return dict, nil
-	}
-
-	var filename string
-	switch version {
-	case model.Version1:
-		filename = "files/tags_v1.yaml"
-	case model.Version11:
-		filename = "files/tags_v11.yaml"
-	case model.Version12:
-		filename = "files/tags_v12.yaml"
-	case model.Version20:
-		filename = "files/tags_v2.yaml"
-
-	default:
-		return []string{}, errors.Errorf("[speller] unknown version %s. failed to load file", version)
+// drawTag selects tag from category by entropy. If tag conflicts with rules, it is re-drawn (MaxRedraws times, then place is skipped, nil is returned)
+func (s *Speller) drawTag(ctx context.Context, tags *spellTags, category Category, weights []uint) ([]string, error) {
+	for attempt := 0; attempt <= MaxRedraws; attempt++ {
+		// rules are checked before decision is saved, so re-drawn tag is marked as rejected in decisions
+		var added []string
+		var conflict error
+		checked := false
+		verdictCtx := entropy2.WithVerdict(ctx, func(idx uint) bool {
+			added, conflict = tags.add(category.Tags[idx].Tag, category.Name)
+			checked = true
+			return conflict == nil
+		})
+		idx, err := s.entropy.SelectWeighted(verdictCtx, weights)
+		if err != nil {
+			return nil, errors.Wrapf(err, "[speller][generateTags] failed get tag number in category %s", category.Name)
+		}
+		if !checked {
+			added, conflict = tags.add(category.Tags[idx].Tag, category.Name) // entropy without audit
+		}
+		if conflict == nil {
+			return added, nil
+		}
+		log.Info().Msgf("[speller] re-draw tag in category %s: %s", category.Name, conflict)
 	}
+	log.Warn().Msgf("[speller] no tag without conflicts in category %s after %d re-draws, skip", category.Name, MaxRedraws)
+	return nil, nil
+}
 
-	log.Info().Msgf("[speller] loading tags file %s", filename)
-	yamlFile, err := os.ReadFile(filename)
-	if err != nil {
-		return []string{}, errors.Wrap(err, "failed to load yaml file")
-	}
-	tags := []string{}
-	err = yaml.Unmarshal(yamlFile, &tags)
+// selectVersion selects one of enabled versions of registry proportionally to their weights
+func (s *Speller) selectVersion(ctx context.Context) (Version, error) {
+	ids, weights := s.registry.Enabled()
+	idx, err := s.entropy.SelectWeighted(entropy2.WithLabels(entropy2.WithPurpose(ctx, model.DecisionPurposeVersion), ids), weights)
 	if err != nil {
-		return []string{}, errors.Wrap(err, "failed to parse yaml file")
+		return Version{}, errors.Wrap(err, "[speller] failed to select version from entropy")
 	}
-	log.Info().Msgf("[speller] version=%s, file=%s, loaded tags: %d. First ten: %s", version, filename, len(tags), strings.Join(tags[0:10], ", "))
-	s.dictionaries[version] = tags
-
-	return s.dictionaries[version], nil
-}
-
-func (s *Speller) selectVersion(ctx context.Context) (string, error) {
-	count := len(model.AvailableVersions)
-	idx, err := s.entropy.Select(ctx, uint(count))
-	return model.AvailableVersions[idx], errors.Wrap(err, "[speller] failed to select version from entropy")
+	return s.registry.Get(ids[idx])
 }
 
 func (s *Speller) notify(ctx context.Context, state *model.CreationState) {
+	if s.notifier == nil {
+		return // preview speller without soul
+	}
 	if err := s.notifier.NotifyCreationState(ctx, *state); err != nil {
 		log.Error().Err(err).Msgf("[speller] failed notify artist state")
 	}
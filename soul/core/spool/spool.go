@@ -0,0 +1,118 @@
+package spool
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// kinds of spooled images
+const (
+	KindFullsize = "fullsize" // full-size jpeg for storage (saver.SaveFullsize)
+	KindMemory   = "f"        // F-size jpeg for memory (saver.SaveArt)
+)
+
+var spoolFile = regexp.MustCompile(`^art-(\d+)-(fullsize|f)\.jpg$`)
+
+/*
+Spool keeps images of arts on local disk until they are uploaded: <dir>/art-<id>-<kind>.jpg.
+File is written into temporary file, synced and renamed, so spool has only complete images after crash.
+*/
+type Spool struct {
+	dir   string
+	mutex sync.Mutex
+}
+
+func NewSpool(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "[spool] failed to create %s", dir)
+	}
+	return &Spool{dir: dir}, nil
+}
+
+func (s *Spool) Put(artID uint, kind string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "[spool] failed to create temporary file")
+	}
+	defer os.Remove(tmp.Name()) // no-op after rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "[spool] failed to write %s of art %d", kind, artID)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "[spool] failed to sync %s of art %d", kind, artID)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "[spool] failed to close %s of art %d", kind, artID)
+	}
+	if err := os.Rename(tmp.Name(), s.filename(artID, kind)); err != nil {
+		return errors.Wrapf(err, "[spool] failed to put %s of art %d", kind, artID)
+	}
+	if dir, err := os.Open(s.dir); err == nil {
+		_ = dir.Sync() // rename survives power loss
+		dir.Close()
+	}
+	return nil
+}
+
+// Get returns spooled image (os.ErrNotExist if image is not in spool)
+func (s *Spool) Get(artID uint, kind string) ([]byte, error) {
+	return os.ReadFile(s.filename(artID, kind))
+}
+
+func (s *Spool) Has(artID uint, kind string) bool {
+	_, err := os.Stat(s.filename(artID, kind))
+	return err == nil
+}
+
+// HasArt - some image of art is not uploaded yet
+func (s *Spool) HasArt(artID uint) bool {
+	return s.Has(artID, KindFullsize) || s.Has(artID, KindMemory)
+}
+
+func (s *Spool) Remove(artID uint, kind string) error {
+	err := os.Remove(s.filename(artID, kind))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Wrapf(err, "[spool] failed to remove %s of art %d", kind, artID)
+	}
+	return nil
+}
+
+// Pending returns sorted ids of arts with images in spool
+func (s *Spool) Pending() ([]uint, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[spool] failed to read %s", s.dir)
+	}
+	found := make(map[uint]bool)
+	for _, entry := range entries {
+		match := spoolFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		id, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		found[uint(id)] = true
+	}
+	ids := make([]uint, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (s *Spool) filename(artID uint, kind string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("art-%d-%s.jpg", artID, kind))
+}
@@ -0,0 +1,189 @@
+package spool
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testSaver struct {
+	down     bool
+	fullsize []uint
+	memory   []uint
+}
+
+func (s *testSaver) SaveFullsize(ctx context.Context, artID uint, imageData []byte) error {
+	if s.down {
+		return errors.New("connection refused")
+	}
+	s.fullsize = append(s.fullsize, artID)
+	return nil
+}
+
+func (s *testSaver) SaveArt(ctx context.Context, artID uint, imageData []byte) error {
+	if s.down {
+		return errors.New("connection refused")
+	}
+	s.memory = append(s.memory, artID)
+	return nil
+}
+
+type testRepository struct {
+	states map[uint]string
+}
+
+func (r *testRepository) SetArtState(ctx context.Context, artID uint, state string) error {
+	r.states[artID] = state
+	return nil
+}
+
+func (r *testRepository) GetArt(ctx context.Context, ID uint) (model.Art, error) {
+	return model.Art{ID: ID, State: r.states[ID]}, nil
+}
+
+type testNotifier struct {
+	prehot []model.Art
+	cards  []model.Art
+}
+
+func (n *testNotifier) NotifyPrehotCard(ctx context.Context, card model.Art) error {
+	n.prehot = append(n.prehot, card)
+	return nil
+}
+
+func (n *testNotifier) NotifyNewCard(ctx context.Context, card model.Art) error {
+	n.cards = append(n.cards, card)
+	return nil
+}
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	spl, err := NewSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{12, 3} {
+		if err := spl.Put(id, KindFullsize, []byte{1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := spl.Put(id, KindMemory, []byte{2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// garbage and unfinished temporary files are not arts
+	if err := os.WriteFile(filepath.Join(dir, ".tmp-123"), []byte{1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := spl.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 12 {
+		t.Fatalf("unexpected pending arts: %v", ids)
+	}
+	if data, err := spl.Get(3, KindMemory); err != nil || len(data) != 1 || data[0] != 2 {
+		t.Fatalf("unexpected image %v, err %v", data, err)
+	}
+
+	if err := spl.Remove(3, KindFullsize); err != nil {
+		t.Fatal(err)
+	}
+	if !spl.HasArt(3) || spl.Has(3, KindFullsize) {
+		t.Fatal("only full-size image must be removed")
+	}
+	if err := spl.Remove(3, KindMemory); err != nil {
+		t.Fatal(err)
+	}
+	if err := spl.Remove(3, KindMemory); err != nil {
+		t.Fatalf("removal of removed image must be ok: %s", err)
+	}
+	if _, err := spl.Get(3, KindMemory); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist, got %v", err)
+	}
+	if ids, _ := spl.Pending(); len(ids) != 1 || ids[0] != 12 {
+		t.Fatalf("unexpected pending arts after removal: %v", ids)
+	}
+}
+
+func TestUploader(t *testing.T) {
+	spl, err := NewSpool(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sav := &testSaver{}
+	repo := &testRepository{states: make(map[uint]string)}
+	ntf := &testNotifier{}
+	upl := NewUploader(spl, sav, repo, ntf, 0)
+	ctx := context.Background()
+
+	// Store only spools images, art is painted until Run uploads it
+	if err := upl.Store(ctx, 1, []byte{1}, []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	if !upl.Spooled(1) || len(sav.fullsize) != 0 || repo.states[1] != "" {
+		t.Fatalf("art must stay painted in spool, uploaded %v, state %s", sav.fullsize, repo.states[1])
+	}
+	select {
+	case <-upl.wake:
+	default:
+		t.Fatal("Store must wake Run up")
+	}
+
+	// storage is down: art stays in spool
+	sav.down = true
+	upl.drain(ctx)
+	if !upl.Spooled(1) || repo.states[1] != "" {
+		t.Fatalf("art must stay painted in spool, got %s", repo.states[1])
+	}
+
+	// backoff is not over
+	sav.down = false
+	upl.drain(ctx)
+	if len(sav.fullsize) != 0 {
+		t.Fatalf("art must not be uploaded before backoff: %v", sav.fullsize)
+	}
+
+	upl.retries[1].next = time.Now()
+	upl.drain(ctx)
+	if upl.Spooled(1) || repo.states[1] != model.ArtStatePublished {
+		t.Fatalf("art must be published from spool, state %s", repo.states[1])
+	}
+	if len(sav.fullsize) != 1 || len(sav.memory) != 1 {
+		t.Fatalf("images must be uploaded once: %v %v", sav.fullsize, sav.memory)
+	}
+	if len(ntf.prehot) != 1 || ntf.prehot[0].ID != 1 {
+		t.Fatalf("published art must be prehot: %+v", ntf.prehot)
+	}
+	if len(ntf.cards) != 1 || ntf.cards[0].ID != 1 || ntf.cards[0].State != model.ArtStatePublished {
+		t.Fatalf("published art must be announced: %+v", ntf.cards)
+	}
+
+	// art, which is being spooled right now, is not uploaded without its F-size image
+	if err := spl.Put(2, KindFullsize, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	upl.storing[2] = true
+	upl.drain(ctx)
+	if len(sav.fullsize) != 1 || !upl.Spooled(2) {
+		t.Fatalf("art must not be uploaded while it is stored: %v", sav.fullsize)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[uint]time.Duration{
+		1:  BackoffMin,
+		2:  BackoffMin * 2,
+		4:  BackoffMin * 8,
+		50: BackoffMax,
+	}
+	for attempts, expected := range cases {
+		if delay := Backoff(attempts); delay != expected {
+			t.Errorf("attempts=%d: expected %s, got %s", attempts, expected, delay)
+		}
+	}
+}
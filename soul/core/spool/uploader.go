@@ -0,0 +1,259 @@
+package spool
+
+import (
+	"context"
+	"github.com/artchitector/artchitect/model"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	PollInterval  = time.Second * 5
+	UploadTimeout = time.Minute * 2 // one upload of one image
+	BackoffMin    = time.Second * 5
+	BackoffMax    = time.Minute * 10
+)
+
+type saver interface {
+	SaveArt(ctx context.Context, artID uint, imageData []byte) error
+	SaveFullsize(ctx context.Context, artID uint, imageData []byte) error
+}
+
+type artRepository interface {
+	SetArtState(ctx context.Context, artID uint, state string) error
+	GetArt(ctx context.Context, ID uint) (model.Art, error)
+}
+
+type notifier interface {
+	NotifyPrehotCard(ctx context.Context, card model.Art) error
+	NotifyNewCard(ctx context.Context, card model.Art) error
+}
+
+type retry struct {
+	attempts uint
+	next     time.Time
+}
+
+/*
+Uploader drains spool: full-size image goes to storage (art becomes stored), then F-size image goes to memory
+(art becomes published). Image is removed from spool only after upload, failed art is retried with exponential backoff.
+Only Run uploads, so creation of arts does not wait for storage and memory. Published arts are prehot and announced
+as new cards.
+*/
+type Uploader struct {
+	spool         *Spool
+	saver         saver
+	artRepository artRepository
+	notifier      notifier
+	prehotDelay   uint          // in seconds
+	wake          chan struct{} // Store wakes Run up, so new art is not waiting for PollInterval
+
+	mutex   sync.Mutex // retries and arts, which are being spooled by Store right now
+	retries map[uint]*retry
+	storing map[uint]bool
+}
+
+func NewUploader(spool *Spool, saver saver, artRepository artRepository, notifier notifier, prehotDelay uint) *Uploader {
+	return &Uploader{
+		spool:         spool,
+		saver:         saver,
+		artRepository: artRepository,
+		notifier:      notifier,
+		prehotDelay:   prehotDelay,
+		wake:          make(chan struct{}, 1),
+		retries:       make(map[uint]*retry),
+		storing:       make(map[uint]bool),
+	}
+}
+
+/*
+Store puts images of art into spool (after that they are not lost, even if soul is restarted) and returns at once,
+art stays painted until Run uploads it. Error is returned only if images are not spooled.
+*/
+func (u *Uploader) Store(ctx context.Context, artID uint, fullsize []byte, f []byte) error {
+	u.mutex.Lock()
+	u.storing[artID] = true // Run must not upload full-size image without F-size image
+	u.mutex.Unlock()
+	defer func() {
+		u.mutex.Lock()
+		delete(u.storing, artID)
+		u.mutex.Unlock()
+	}()
+
+	if err := u.spool.Put(artID, KindFullsize, fullsize); err != nil {
+		return err
+	}
+	if err := u.spool.Put(artID, KindMemory, f); err != nil {
+		_ = u.spool.Remove(artID, KindFullsize)
+		return err
+	}
+	select {
+	case u.wake <- struct{}{}:
+	default: // Run is woken up already
+	}
+	return nil
+}
+
+// Spooled - art has images, which are not uploaded yet
+func (u *Uploader) Spooled(artID uint) bool {
+	return u.spool.HasArt(artID)
+}
+
+// uploadWithRetry uploads spooled images of art, failed art gets next backoff
+func (u *Uploader) uploadWithRetry(ctx context.Context, artID uint) (string, error) {
+	state, err := u.upload(ctx, artID)
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if err != nil {
+		u.failed(artID)
+	} else {
+		delete(u.retries, artID)
+	}
+	return state, err
+}
+
+func (u *Uploader) upload(ctx context.Context, artID uint) (string, error) {
+	state := model.ArtStatePainted
+	if data, err := u.spool.Get(artID, KindFullsize); err == nil {
+		uploadCtx, cancel := context.WithTimeout(ctx, UploadTimeout)
+		err := u.saver.SaveFullsize(uploadCtx, artID, data)
+		cancel()
+		if err != nil {
+			return state, errors.Wrapf(err, "[uploader] failed to upload full-size image of art %d", artID)
+		}
+		if err := u.artRepository.SetArtState(ctx, artID, model.ArtStateStored); err != nil {
+			return state, err // image stays in spool, upload will be repeated
+		}
+		if err := u.spool.Remove(artID, KindFullsize); err != nil {
+			return state, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return state, errors.Wrapf(err, "[uploader] failed to read full-size image of art %d", artID)
+	}
+	state = model.ArtStateStored
+
+	data, err := u.spool.Get(artID, KindMemory)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, errors.Errorf("[uploader] no F-size image of art %d in spool", artID)
+	} else if err != nil {
+		return state, errors.Wrapf(err, "[uploader] failed to read F-size image of art %d", artID)
+	}
+	uploadCtx, cancel := context.WithTimeout(ctx, UploadTimeout)
+	err = u.saver.SaveArt(uploadCtx, artID, data)
+	cancel()
+	if err != nil {
+		return state, errors.Wrapf(err, "[uploader] failed to upload F-size image of art %d", artID)
+	}
+	if err := u.artRepository.SetArtState(ctx, artID, model.ArtStatePublished); err != nil {
+		return state, err
+	}
+	if err := u.spool.Remove(artID, KindMemory); err != nil {
+		log.Error().Err(err).Msgf("[uploader] art %d is published, but its image stays in spool", artID)
+	}
+	return model.ArtStatePublished, nil
+}
+
+// Run uploads arts of spool (also arts left by previous run of soul) until ctx is done
+func (u *Uploader) Run(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+	for {
+		u.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-u.wake:
+		}
+	}
+}
+
+func (u *Uploader) drain(ctx context.Context) {
+	ids, err := u.spool.Pending()
+	if err != nil {
+		log.Error().Err(err).Msgf("[uploader] failed to list spool")
+		return
+	}
+	for _, id := range ids {
+		if ctx.Err() != nil || !u.ready(id) {
+			continue
+		}
+		state, err := u.uploadWithRetry(ctx, id)
+		if err != nil {
+			log.Error().Err(err).Msgf("[uploader] art %d is %s, retry in %s", id, state, u.delay(id))
+			continue
+		}
+		log.Info().Msgf("[uploader] art %d is published from spool", id)
+		u.notify(ctx, id)
+	}
+}
+
+// notify prehots published art and announces it as new card (notifier is not set in arts_report command)
+func (u *Uploader) notify(ctx context.Context, artID uint) {
+	if u.notifier == nil {
+		return
+	}
+	art, err := u.artRepository.GetArt(ctx, artID)
+	if err != nil {
+		log.Error().Err(err).Msgf("[uploader] failed to get published art %d", artID)
+		return
+	}
+	if err := u.notifier.NotifyPrehotCard(ctx, art); err != nil {
+		log.Error().Err(err).Msgf("[uploader] failed to notify prehot card %d", artID)
+	}
+	// give time to prehot cache
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Second * time.Duration(u.prehotDelay)):
+	}
+	if err := u.notifier.NotifyNewCard(ctx, art); err != nil {
+		log.Error().Err(err).Msgf("[uploader] failed to notify new card %d", artID)
+	}
+}
+
+// ready - art is spooled completely and its backoff is over
+func (u *Uploader) ready(artID uint) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.storing[artID] {
+		return false
+	}
+	r, found := u.retries[artID]
+	return !found || !time.Now().Before(r.next)
+}
+
+func (u *Uploader) delay(artID uint) time.Duration {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if r, found := u.retries[artID]; found {
+		return time.Until(r.next).Round(time.Second)
+	}
+	return 0
+}
+
+// failed sets next backoff of art. Caller holds mutex
+func (u *Uploader) failed(artID uint) {
+	r, found := u.retries[artID]
+	if !found {
+		r = &retry{}
+		u.retries[artID] = r
+	}
+	r.attempts++
+	r.next = time.Now().Add(Backoff(r.attempts))
+}
+
+// Backoff - delay after attempts failed uploads: BackoffMin, x2 every attempt, not more than BackoffMax
+func Backoff(attempts uint) time.Duration {
+	delay := BackoffMin
+	for i := uint(1); i < attempts && delay < BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > BackoffMax {
+		delay = BackoffMax
+	}
+	return delay
+}
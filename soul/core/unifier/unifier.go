@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/artchitector/artchitect/model"
+	entropy2 "github.com/artchitector/artchitect/soul/core/entropy"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -336,7 +337,7 @@ func (u *Unifier) promoteLeads(ctx context.Context, unity model.Unity, state *mo
 			default:
 			}
 
-			selection, err := u.entropy.Select(ctx, model.Rank100)
+			selection, err := u.entropy.Select(entropy2.WithPurpose(ctx, model.DecisionPurposeUnityLead), model.Rank100)
 			if err != nil {
 				return model.Unity{}, errors.Wrapf(err, "[unifier] failed to get data from entropy")
 			}
@@ -379,7 +380,7 @@ func (u *Unifier) promoteLeads(ctx context.Context, unity model.Unity, state *mo
 			return model.Unity{}, errors.Errorf("[unifier] wrong rank %d", unity.Rank)
 		}
 		for len(currentLeaders) < leadersCount {
-			selection, err := u.entropy.Select(ctx, uint(len(allLeaders)))
+			selection, err := u.entropy.Select(entropy2.WithPurpose(ctx, model.DecisionPurposeUnityLead), uint(len(allLeaders)))
 			if err != nil {
 				return model.Unity{}, errors.Errorf("[unifier] failed get selection from entropy. all lead count: %d", len(allLeaders))
 			}
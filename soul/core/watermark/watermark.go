@@ -16,6 +16,10 @@ import (
 	"os"
 )
 
+// Version - version of watermark drawing (cat icon files/watermark.png with card number), saved in art provenance.
+// Change it when drawing is changed
+const Version = "2"
+
 type Watermark struct {
 	font   *truetype.Font
 	catImg image.Image
@@ -25,6 +29,10 @@ func NewWatermark() *Watermark {
 	return &Watermark{}
 }
 
+func (w *Watermark) Version() string {
+	return Version
+}
+
 // RU: Так как тут сложно, многие комментарии будут на русском
 
 func (w *Watermark) AddArtWatermark(originalImage image.Image, cardID uint) (image.Image, error) {
@@ -6,6 +6,7 @@ require (
 	github.com/artchitector/artchitect/memory v0.0.0-20230206141224-ef4d2c479ec6
 	github.com/artchitector/artchitect/model v0.0.0-20230218112449-15e526fcb934
 	github.com/artchitector/artchitect/resizer v0.0.0-20230203133021-ba066d64422a
+	github.com/blackjack/webcam v0.0.0-20220329180758-ba064708e165
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/go-telegram/bot v0.5.1
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
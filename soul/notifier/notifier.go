@@ -95,6 +95,15 @@ func (n *Notifier) NotifyEntropy(ctx context.Context, state model.EntropyState)
 	return errors.Wrap(err, "[notifier] failed to notify phase")
 }
 
+func (n *Notifier) NotifyEntropyHealth(ctx context.Context, health model.EntropyHealth) error {
+	jsn, err := json.Marshal(health)
+	if err != nil {
+		return errors.Wrap(err, "[notifier] failed marshal entropy health payload")
+	}
+	err = n.publish(ctx, model.ChannelEntropyHealth, jsn)
+	return errors.Wrap(err, "[notifier] failed to notify entropy health")
+}
+
 func (n *Notifier) publish(ctx context.Context, channel string, data interface{}) error {
 	for key, r := range n.redises {
 		if err := r.Publish(ctx, channel, data).Err(); err != nil {
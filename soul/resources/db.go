@@ -32,6 +32,9 @@ func initDB(env *Env) *gorm.DB {
 		log.Fatal().Err(errors.Wrap(err, "failed to connect to postgres"))
 	}
 
+	if err := db.Exec(model.TagsExtension).Error; err != nil {
+		log.Fatal().Err(errors.Wrap(err, "failed to create extension of tags")).Send()
+	}
 	if err := db.AutoMigrate(
 		&model.Art{},
 		&model.Spell{},
@@ -40,6 +43,9 @@ func initDB(env *Env) *gorm.DB {
 		&model.Selection{},
 		&model.Like{},
 		&model.Unity{},
+		&model.Decision{},
+		&model.Tag{},
+		&model.SpellTag{},
 	); err != nil {
 		log.Fatal().Err(errors.Wrap(err, "failed to auto-migrate"))
 	}
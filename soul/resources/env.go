@@ -5,6 +5,16 @@ import (
 	"github.com/rs/zerolog/log"
 	"os"
 	"strconv"
+	"time"
+)
+
+const (
+	EntropySourceWebcam  = "webcam"  // frames from origin (webcam), default
+	EntropySourceV4L2    = "v4l2"    // frames directly from V4L2 camera (V4L2_DEVICE), origin is fallback
+	EntropySourceFrames  = "frames"  // recorded frames from directory, replayed in a loop
+	EntropySourceUrandom = "urandom" // /dev/urandom, for machines without camera
+	EntropySourcePrng    = "prng"    // deterministic seeded PRNG, for local usage only
+	EntropySourceJournal = "journal" // replay of recorded session (ENTROPY_JOURNAL_DIR)
 )
 
 type Env struct {
@@ -18,6 +28,7 @@ type Env struct {
 	Telegram10BotEnabled bool
 	TelegramABotEnabled  bool
 	StorageEnabled       bool
+	ReconcilerEnabled    bool // finish or remove arts stuck between painting and publishing
 
 	// external resources
 	DbDSN           string
@@ -25,10 +36,31 @@ type Env struct {
 	RedisHostEU     string
 	RedisPassword   string
 	OriginURL       string
+	OriginRaw       bool // take uncompressed frames from origin /raw instead of jpeg
 	ArtistURL       string
+	ArtistBackends  string // yaml-file with backends of artist router (empty - single artist ARTIST_URL)
+	ArtistAsync     bool   // artist ARTIST_URL has job protocol with progress
+	ArtistPreviews  bool   // send intermediate latents of painting to site
 	MemorySaverURL  string
 	MemoryHost      string
 	StorageSaverURL string
+	SpoolDir        string // images of arts wait here until they are uploaded to storage and memory
+
+	// entropy
+	EntropySource        string
+	EntropyFramesDir     string
+	EntropySeed          int64
+	EntropyRecordDir     string        // if not empty, lightmaster records frames and decisions here
+	EntropyRecordMaxSize int64         // max size of recorded frames in bytes, older frames are removed (0 - no limit)
+	EntropyRecordMaxAge  time.Duration // max age of recorded frames (0 - no limit)
+	EntropyJournalDir    string        // session directory for journal replay
+	EntropyHealthStrict  bool          // refuse selections while entropy stream is unhealthy
+	EntropyPipeline      string        // yaml-file with lightmaster pipeline config (empty - default geometry)
+	V4L2Device           string        // camera device for ENTROPY_SOURCE=v4l2
+	V4L2Size             string        // frame size "WxH" (empty - largest one)
+
+	// speller
+	VersionsDir string // manifest directory of card generation versions (reloaded on SIGHUP or change)
 
 	// settings
 	ArtTotalTime       uint
@@ -72,6 +104,46 @@ func initEnv() *Env {
 		log.Fatal().Err(err)
 	}
 
+	entropySource := os.Getenv("ENTROPY_SOURCE")
+	if entropySource == "" {
+		entropySource = EntropySourceWebcam
+	}
+	v4l2Device := os.Getenv("V4L2_DEVICE")
+	if v4l2Device == "" {
+		v4l2Device = "/dev/video0"
+	}
+	versionsDir := os.Getenv("VERSIONS_DIR")
+	if versionsDir == "" {
+		versionsDir = "files/versions"
+	}
+	spoolDir := os.Getenv("SPOOL_DIR")
+	if spoolDir == "" {
+		spoolDir = "files/spool"
+	}
+	var entropySeed int64
+	if entropySeedStr := os.Getenv("ENTROPY_SEED"); entropySeedStr != "" {
+		entropySeed, err = strconv.ParseInt(entropySeedStr, 10, 64)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[env] wrong ENTROPY_SEED %s", entropySeedStr)
+		}
+	}
+
+	// recording of every frame fills disk, so frames are limited by default
+	entropyRecordMaxSize := int64(1024)
+	if maxSizeStr := os.Getenv("ENTROPY_RECORD_MAX_SIZE"); maxSizeStr != "" {
+		entropyRecordMaxSize, err = strconv.ParseInt(maxSizeStr, 10, 64)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[env] wrong ENTROPY_RECORD_MAX_SIZE %s", maxSizeStr)
+		}
+	}
+	entropyRecordMaxAge := time.Hour * 72
+	if maxAgeStr := os.Getenv("ENTROPY_RECORD_MAX_AGE"); maxAgeStr != "" {
+		entropyRecordMaxAge, err = time.ParseDuration(maxAgeStr)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("[env] wrong ENTROPY_RECORD_MAX_AGE %s", maxAgeStr)
+		}
+	}
+
 	return &Env{
 		LotteryEnabled:       os.Getenv("LOTTERY_ENABLED") == "true",
 		CardCreationEnabled:  os.Getenv("CARDS_CREATION_ENABLED") == "true",
@@ -82,16 +154,36 @@ func initEnv() *Env {
 		Telegram10BotEnabled: os.Getenv("TELEGRAM_10BOT_ENABLE") == "true",
 		TelegramABotEnabled:  os.Getenv("TELEGRAM_ABOT_ENABLE") == "true",
 		StorageEnabled:       os.Getenv("STORAGE_ENABLED") == "true",
+		ReconcilerEnabled:    os.Getenv("RECONCILER_ENABLED") == "true",
 
 		DbDSN:           os.Getenv("DB_DSN"),
 		RedisHostRU:     os.Getenv("REDIS_HOST_RU"),
 		RedisHostEU:     os.Getenv("REDIS_HOST_EU"),
 		RedisPassword:   os.Getenv("REDIS_PASSWORD"),
 		OriginURL:       os.Getenv("ORIGIN_URL"),
+		OriginRaw:       os.Getenv("ORIGIN_RAW") == "true",
 		ArtistURL:       os.Getenv("ARTIST_URL"),
+		ArtistBackends:  os.Getenv("ARTIST_BACKENDS"),
+		ArtistAsync:     os.Getenv("ARTIST_ASYNC") == "true",
+		ArtistPreviews:  os.Getenv("ARTIST_PREVIEWS") == "true",
 		MemoryHost:      os.Getenv("MEMORY_HOST"),
 		MemorySaverURL:  os.Getenv("MEMORY_SAVER_URL"),
 		StorageSaverURL: os.Getenv("STORAGE_SAVER_URL"),
+		SpoolDir:        spoolDir,
+
+		EntropySource:        entropySource,
+		EntropyFramesDir:     os.Getenv("ENTROPY_FRAMES_DIR"),
+		EntropySeed:          entropySeed,
+		EntropyRecordDir:     os.Getenv("ENTROPY_RECORD_DIR"),
+		EntropyRecordMaxSize: entropyRecordMaxSize * 1024 * 1024,
+		EntropyRecordMaxAge:  entropyRecordMaxAge,
+		EntropyJournalDir:    os.Getenv("ENTROPY_JOURNAL_DIR"),
+		EntropyHealthStrict:  os.Getenv("ENTROPY_HEALTH_STRICT") == "true",
+		EntropyPipeline:      os.Getenv("ENTROPY_PIPELINE"),
+		V4L2Device:           v4l2Device,
+		V4L2Size:             os.Getenv("V4L2_SIZE"),
+
+		VersionsDir: versionsDir,
 
 		ArtTotalTime:       uint(artTotalTime),
 		PrehotDelay:        uint(prehotDelay),
@@ -0,0 +1,98 @@
+package resources
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const FramesDirectoryInterval = time.Millisecond * 200 // примерно как отдаёт кадры origin
+
+/*
+FramesDirectory заменяет веб-камеру: отдаёт записанные кадры (jpeg/png) из директории по кругу.
+Кадры отдаются в порядке имён файлов.
+*/
+type FramesDirectory struct {
+	dir      string
+	interval time.Duration
+}
+
+func (f *FramesDirectory) GetStream(ctx context.Context) chan image.Image {
+	ch := make(chan image.Image)
+	go func() {
+		for {
+			files, err := f.listFrames()
+			if err != nil {
+				log.Error().Err(err).Msgf("[frames] failed to list frames")
+			} else if len(files) == 0 {
+				log.Error().Msgf("[frames] no frames in directory %s", f.dir)
+			}
+			if err != nil || len(files) == 0 {
+				select {
+				case <-ctx.Done():
+					log.Info().Msg("[frames] stop reading frames")
+					return
+				case <-time.After(time.Second * 5):
+					continue
+				}
+			}
+
+			for _, file := range files {
+				img, err := f.readFrame(file)
+				if err != nil {
+					log.Error().Err(err).Msgf("[frames] failed readFrame %s", file)
+					continue
+				}
+				select {
+				case <-ctx.Done():
+					log.Info().Msg("[frames] stop reading frames")
+					return
+				case ch <- img:
+				}
+				<-time.After(f.interval)
+			}
+		}
+	}()
+	return ch
+}
+
+func (f *FramesDirectory) listFrames() ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[frames] failed to read dir %s", f.dir)
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jpg", ".jpeg", ".png":
+			files = append(files, filepath.Join(f.dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (f *FramesDirectory) readFrame(filename string) (image.Image, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[frames] failed to open %s", filename)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[frames] failed to decode %s", filename)
+	}
+	return toRGBA(img), nil
+}
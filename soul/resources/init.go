@@ -10,6 +10,8 @@ type Resources struct {
 	db      *gorm.DB
 	redises map[string]*redis.Client
 	webcam  *Webcam
+	v4l2    *V4L2Camera
+	frames  *FramesDirectory
 }
 
 func (r *Resources) GetDB() *gorm.DB {
@@ -28,10 +30,33 @@ func (r *Resources) GetWebcam() *Webcam {
 	return r.webcam
 }
 
+func (r *Resources) GetV4L2Camera() *V4L2Camera {
+	return r.v4l2
+}
+
+func (r *Resources) GetFramesDirectory() *FramesDirectory {
+	return r.frames
+}
+
 func InitResources() *Resources {
 	env := initEnv()
 	db := initDB(env)
 	redises := initRedises(env)
 
-	return &Resources{env, db, redises, &Webcam{env.OriginURL}}
+	webcam := NewWebcam(env.OriginURL, env.OriginRaw)
+
+	return &Resources{
+		env,
+		db,
+		redises,
+		webcam,
+		NewV4L2Camera(NewV4L2Device(env.V4L2Device, env.V4L2Size), webcam),
+		&FramesDirectory{env.EntropyFramesDir, FramesDirectoryInterval},
+	}
+}
+
+// InitDBResources - only env and database (for maintenance commands in cmd/*)
+func InitDBResources() *Resources {
+	env := initEnv()
+	return &Resources{env: env, db: initDB(env)}
 }
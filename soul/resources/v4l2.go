@@ -0,0 +1,154 @@
+package resources
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"image"
+	"image/color"
+	"time"
+)
+
+const (
+	V4L2FrameTimeout = 5 // seconds, WaitForFrame timeout
+	V4L2MaxFailures  = 5 // after so many failures in a row camera is closed and HTTP origin is used
+
+	RetryMinDelay = time.Millisecond * 100
+	RetryMaxDelay = time.Second * 5
+)
+
+var ErrFrameTimeout = errors.New("[v4l2] frame timeout")
+
+// frameProvider - поставщик сырых YUYV-кадров (V4L2-устройство, в тестах - фейк)
+type frameProvider interface {
+	Start() (width uint32, height uint32, err error)
+	ReadFrame(timeout uint32) ([]byte, error) // ErrFrameTimeout, если кадра не было
+	Close() error
+}
+
+/*
+V4L2Camera читает кадры с веб-камеры прямо в процессе soul, без origin-сервера.
+Кадры берутся в YUYV и переводятся в RGBA без JPEG-сжатия: JPEG уничтожает как раз тот шум сенсора, из которого строится энтропия.
+
+Если камера не открывается или постоянно ошибается, поток переключается на HTTP origin (fallback, может быть nil).
+*/
+type V4L2Camera struct {
+	provider frameProvider
+	fallback *Webcam
+}
+
+func NewV4L2Camera(provider frameProvider, fallback *Webcam) *V4L2Camera {
+	return &V4L2Camera{provider, fallback}
+}
+
+func (c *V4L2Camera) GetStream(ctx context.Context) chan image.Image {
+	ch := make(chan image.Image)
+	go func() {
+		defer func() {
+			if err := c.provider.Close(); err != nil {
+				log.Error().Err(err).Msgf("[v4l2] failed to close camera")
+			}
+		}()
+		if err := c.stream(ctx, ch); err != nil {
+			if c.fallback == nil {
+				log.Error().Err(err).Msgf("[v4l2] camera failed, no fallback, stop reading")
+				return
+			}
+			log.Error().Err(err).Msgf("[v4l2] camera failed, fallback to HTTP origin %s", c.fallback.originUrl)
+			c.fallback.stream(ctx, ch)
+		}
+	}()
+	return ch
+}
+
+// stream returns nil when ctx is done, or error when camera is broken
+func (c *V4L2Camera) stream(ctx context.Context, ch chan image.Image) error {
+	width, height, err := c.provider.Start()
+	if err != nil {
+		return errors.Wrap(err, "[v4l2] failed to start camera")
+	}
+	log.Info().Msgf("[v4l2] camera started %dx%d", width, height)
+
+	backoff := newBackoff()
+	failures := 0
+	for {
+		if ctx.Err() != nil {
+			log.Info().Msg("[v4l2] stop reading stream")
+			return nil
+		}
+		frame, err := c.provider.ReadFrame(V4L2FrameTimeout)
+		if err == nil {
+			var img image.Image
+			if img, err = yuyvToRGBA(frame, int(width), int(height)); err == nil {
+				failures = 0
+				backoff.reset()
+				select {
+				case <-ctx.Done():
+					return nil
+				case ch <- img:
+				}
+				continue
+			}
+		}
+
+		failures += 1
+		log.Error().Err(err).Msgf("[v4l2] failed to read frame (%d in a row)", failures)
+		if failures >= V4L2MaxFailures {
+			return errors.Wrapf(err, "[v4l2] %d failures in a row", failures)
+		}
+		if !backoff.wait(ctx) {
+			return nil
+		}
+	}
+}
+
+/*
+yuyvToRGBA - YUYV (YUV 4:2:2): 4 байта на 2 пикселя Y0 U Y1 V. Перевод в RGBA по JFIF (полный диапазон), как делал origin через image.YCbCr.
+*/
+func yuyvToRGBA(frame []byte, width int, height int) (*image.RGBA, error) {
+	if width%2 != 0 {
+		return nil, errors.Errorf("[v4l2] odd YUYV width %d", width)
+	}
+	if len(frame) < width*height*2 {
+		return nil, errors.Errorf("[v4l2] short YUYV frame: %d bytes for %dx%d", len(frame), width, height)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x += 2 {
+			i := (y*width + x) * 2
+			y0, u, y1, v := frame[i], frame[i+1], frame[i+2], frame[i+3]
+			r, g, b := color.YCbCrToRGB(y0, u, v)
+			img.SetRGBA(x, y, color.RGBA{R: r, G: g, B: b, A: 255})
+			r, g, b = color.YCbCrToRGB(y1, u, v)
+			img.SetRGBA(x+1, y, color.RGBA{R: r, G: g, B: b, A: 255})
+		}
+	}
+	return img, nil
+}
+
+// backoff - экспоненциальная пауза между повторами (от RetryMinDelay до RetryMaxDelay)
+type backoff struct {
+	delay time.Duration
+}
+
+func newBackoff() *backoff {
+	return &backoff{RetryMinDelay}
+}
+
+// wait returns false if ctx is done
+func (b *backoff) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(b.delay):
+	}
+	b.delay *= 2
+	if b.delay > RetryMaxDelay {
+		b.delay = RetryMaxDelay
+	}
+	return true
+}
+
+func (b *backoff) reset() {
+	b.delay = RetryMinDelay
+}
@@ -0,0 +1,100 @@
+//go:build linux
+
+package resources
+
+import (
+	"github.com/blackjack/webcam"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"sort"
+)
+
+const V4L2PixelFormatYUYV = webcam.PixelFormat(0x56595559)
+
+// V4L2Device - камера через V4L2 (github.com/blackjack/webcam, как в origin). Только YUYV, размер - наибольший или заданный "WxH"
+type V4L2Device struct {
+	path string
+	size string
+	cam  *webcam.Webcam
+}
+
+func NewV4L2Device(path string, size string) *V4L2Device {
+	return &V4L2Device{path: path, size: size}
+}
+
+func (d *V4L2Device) Start() (uint32, uint32, error) {
+	cam, err := webcam.Open(d.path)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "[v4l2] failed to open %s", d.path)
+	}
+	d.cam = cam
+
+	formats := cam.GetSupportedFormats()
+	if _, found := formats[V4L2PixelFormatYUYV]; !found {
+		return 0, 0, errors.Errorf("[v4l2] %s does not support YUYV, formats: %v", d.path, formats)
+	}
+
+	sizes := cam.GetSupportedFrameSizes(V4L2PixelFormatYUYV)
+	if len(sizes) == 0 {
+		return 0, 0, errors.Errorf("[v4l2] %s has no YUYV frame sizes", d.path)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].MaxWidth*sizes[i].MaxHeight < sizes[j].MaxWidth*sizes[j].MaxHeight
+	})
+	size := sizes[len(sizes)-1]
+	if d.size != "" {
+		found := false
+		for _, s := range sizes {
+			if s.GetString() == d.size {
+				size, found = s, true
+				break
+			}
+		}
+		if !found {
+			return 0, 0, errors.Errorf("[v4l2] %s does not support size %s", d.path, d.size)
+		}
+	}
+
+	format, width, height, err := cam.SetImageFormat(V4L2PixelFormatYUYV, size.MaxWidth, size.MaxHeight)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "[v4l2] failed to set image format")
+	}
+	if format != V4L2PixelFormatYUYV {
+		return 0, 0, errors.Errorf("[v4l2] camera set format %s instead of YUYV", formats[format])
+	}
+	log.Info().Msgf("[v4l2] %s: YUYV %dx%d", d.path, width, height)
+
+	if err := cam.StartStreaming(); err != nil {
+		return 0, 0, errors.Wrapf(err, "[v4l2] failed to start streaming")
+	}
+	return width, height, nil
+}
+
+func (d *V4L2Device) ReadFrame(timeout uint32) ([]byte, error) {
+	err := d.cam.WaitForFrame(timeout)
+	if _, ok := err.(*webcam.Timeout); ok {
+		return nil, ErrFrameTimeout
+	} else if err != nil {
+		return nil, errors.Wrap(err, "[v4l2] failed to wait for frame")
+	}
+	frame, err := d.cam.ReadFrame()
+	if err != nil {
+		return nil, errors.Wrap(err, "[v4l2] failed to read frame")
+	}
+	if len(frame) == 0 {
+		return nil, ErrFrameTimeout
+	}
+	// буфер принадлежит драйверу и будет перезаписан, поэтому копируем
+	result := make([]byte, len(frame))
+	copy(result, frame)
+	return result, nil
+}
+
+func (d *V4L2Device) Close() error {
+	if d.cam == nil {
+		return nil
+	}
+	err := d.cam.Close()
+	d.cam = nil
+	return err
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package resources
+
+import (
+	"github.com/pkg/errors"
+)
+
+// V4L2Device - V4L2 есть только в linux, на других системах камера всегда уходит в HTTP fallback
+type V4L2Device struct {
+	path string
+}
+
+func NewV4L2Device(path string, size string) *V4L2Device {
+	return &V4L2Device{path}
+}
+
+func (d *V4L2Device) Start() (uint32, uint32, error) {
+	return 0, 0, errors.Errorf("[v4l2] %s: V4L2 is supported only on linux", d.path)
+}
+
+func (d *V4L2Device) ReadFrame(timeout uint32) ([]byte, error) {
+	return nil, errors.New("[v4l2] V4L2 is supported only on linux")
+}
+
+func (d *V4L2Device) Close() error {
+	return nil
+}
@@ -0,0 +1,100 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeProvider отдаёт заданные кадры, а после них - ошибки
+type fakeProvider struct {
+	width, height uint32
+	frames        [][]byte
+	startErr      error
+}
+
+func (p *fakeProvider) Start() (uint32, uint32, error) {
+	return p.width, p.height, p.startErr
+}
+
+func (p *fakeProvider) ReadFrame(timeout uint32) ([]byte, error) {
+	if len(p.frames) == 0 {
+		return nil, errors.New("device is gone")
+	}
+	frame := p.frames[0]
+	p.frames = p.frames[1:]
+	return frame, nil
+}
+
+func (p *fakeProvider) Close() error {
+	return nil
+}
+
+func TestYuyvToRGBA(t *testing.T) {
+	// 2x1: чёрный и белый пиксели (U и V общие, нейтральные)
+	img, err := yuyvToRGBA([]byte{0, 128, 255, 128}, 2, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if c := img.RGBAAt(0, 0); c != (color.RGBA{R: 0, G: 0, B: 0, A: 255}) {
+		t.Errorf("expected black, got %v", c)
+	}
+	if c := img.RGBAAt(1, 0); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("expected white, got %v", c)
+	}
+
+	if _, err := yuyvToRGBA([]byte{16, 128}, 2, 1); err == nil {
+		t.Errorf("expected error on short frame")
+	}
+}
+
+func TestV4L2CameraStream(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	provider := &fakeProvider{width: 2, height: 1, frames: [][]byte{{0, 128, 255, 128}}}
+	camera := NewV4L2Camera(provider, nil)
+	ch := camera.GetStream(ctx)
+
+	select {
+	case img := <-ch:
+		if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 1 {
+			t.Fatalf("unexpected frame size %v", img.Bounds())
+		}
+		if _, ok := img.At(0, 0).(color.RGBA); !ok {
+			t.Fatalf("lightmaster needs RGBA frames")
+		}
+	case <-ctx.Done():
+		t.Fatalf("no frame from camera")
+	}
+}
+
+func TestV4L2CameraFallback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := new(bytes.Buffer)
+		_ = jpeg.Encode(buf, image.NewRGBA(image.Rect(0, 0, 4, 4)), nil)
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	provider := &fakeProvider{startErr: errors.New("no camera")}
+	camera := NewV4L2Camera(provider, NewWebcam(server.URL, false))
+	select {
+	case img := <-camera.GetStream(ctx):
+		if img.Bounds().Dx() != 4 {
+			t.Fatalf("expected frame from HTTP origin, got %v", img.Bounds())
+		}
+	case <-ctx.Done():
+		t.Fatalf("no frame from fallback")
+	}
+}
@@ -1,54 +1,146 @@
 package resources
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/image/draw"
 	"image"
 	"image/jpeg"
+	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// формат /raw из origin (origin/frames.go): заголовок и кадр как его отдала камера
+const (
+	RawMagic      = "ARAW"
+	RawHeaderSize = 4 + 4 + 4 + 4 + 8 + 4
+	RawFourCCYUYV = 0x56595559
+)
+
+/*
+Webcam получает кадры с origin-сервера. По умолчанию это jpeg ("/"),
+с raw=true - несжатый кадр ("/raw"), в котором сохраняется весь шум сенсора.
+Raw-кадр, уже полученный раньше (по времени кадра из заголовка), отбрасывается: одинаковые кадры дают нулевой шум.
+*/
 type Webcam struct {
 	originUrl string
+	raw       bool
+}
+
+func NewWebcam(originUrl string, raw bool) *Webcam {
+	return &Webcam{originUrl, raw}
 }
 
 func (w *Webcam) GetStream(ctx context.Context) chan image.Image {
 	ch := make(chan image.Image)
-	go func() {
-		for {
+	go w.stream(ctx, ch)
+	return ch
+}
+
+// stream читает кадры с origin, пока не закончится ctx. При ошибках делает паузу (backoff), чтобы не крутиться вхолостую
+func (w *Webcam) stream(ctx context.Context, ch chan image.Image) {
+	backoff := newBackoff()
+	var lastFrameTime time.Time
+	for {
+		if ctx.Err() != nil {
+			log.Info().Msg("[webcam] stop reading stream")
+			return
+		}
+		img, frameTime, err := w.getFrame(ctx)
+		if err != nil {
+			log.Error().Err(err).Msgf("[webcam] failed getFrame")
+			if !backoff.wait(ctx) {
+				log.Info().Msg("[webcam] stop reading stream")
+				return
+			}
+			continue
+		}
+		backoff.reset()
+		if !frameTime.IsZero() && !frameTime.After(lastFrameTime) {
+			// этот кадр уже был (origin отдал /raw без ожидания нового кадра)
+			log.Debug().Msgf("[webcam] skip seen frame %s", frameTime.Format(time.RFC3339Nano))
 			select {
 			case <-ctx.Done():
-				log.Info().Msg("[webcam] stop reading stream")
-			default:
-				if img, err := w.getFrame(ctx); err != nil {
-					log.Error().Err(err).Msgf("[webcam] failed getFrame")
-				} else {
-					ch <- img
-				}
+			case <-time.After(RetryMinDelay):
 			}
+			continue
 		}
-	}()
-	return ch
+		lastFrameTime = frameTime
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("[webcam] stop reading stream")
+			return
+		case ch <- img:
+		}
+	}
 }
 
-func (w *Webcam) getFrame(ctx context.Context) (image.Image, error) {
-	response, err := http.Get(w.originUrl)
+// getFrame returns frame and time of frame (only for raw frames, jpeg frames have zero time)
+func (w *Webcam) getFrame(ctx context.Context) (image.Image, time.Time, error) {
+	url := w.originUrl
+	if w.raw {
+		url = strings.TrimRight(w.originUrl, "/") + "/raw"
+	}
+	response, err := http.Get(url)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to get %s", w.originUrl)
+		return nil, time.Time{}, errors.Wrapf(err, "failed to get %s", url)
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, time.Time{}, errors.Errorf("[webcam] origin %s responded with status %d", url, response.StatusCode)
+	}
+	if w.raw {
+		return decodeRawFrame(response.Body)
+	}
 
 	img, err := jpeg.Decode(response.Body)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to decode image from response.Body")
+		return nil, time.Time{}, errors.Wrap(err, "failed to decode image from response.Body")
+	}
+	img = toRGBA(img)
+	return img, time.Time{}, nil
+}
+
+func decodeRawFrame(r io.Reader) (image.Image, time.Time, error) {
+	header := make([]byte, RawHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "[webcam] failed to read raw frame header")
+	}
+	if string(header[0:4]) != RawMagic {
+		return nil, time.Time{}, errors.Errorf("[webcam] wrong raw frame magic %q", header[0:4])
+	}
+	width := binary.BigEndian.Uint32(header[4:8])
+	height := binary.BigEndian.Uint32(header[8:12])
+	fourcc := binary.BigEndian.Uint32(header[12:16])
+	frameTime := time.Unix(0, int64(binary.BigEndian.Uint64(header[16:24])))
+	length := binary.BigEndian.Uint32(header[24:28])
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "[webcam] failed to read raw frame of %d bytes", length)
+	}
+
+	if fourcc == RawFourCCYUYV {
+		img, err := yuyvToRGBA(frame, int(width), int(height))
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return img, frameTime, nil
+	}
+	// другие форматы камеры (MJPEG) и так сжаты
+	img, err := jpeg.Decode(bytes.NewReader(frame))
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "[webcam] failed to decode raw frame with fourcc %x", fourcc)
 	}
-	img = w.yCrCb2RGBA(img)
-	return img, nil
+	return toRGBA(img), frameTime, nil
 }
 
-func (w *Webcam) yCrCb2RGBA(img image.Image) image.Image {
+// toRGBA - lightmaster работает только с color.RGBA, поэтому все кадры приводятся к *image.RGBA
+func toRGBA(img image.Image) image.Image {
 	b := img.Bounds()
 	m := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
 	draw.Draw(m, m.Bounds(), img, b.Min, draw.Src)
@@ -0,0 +1,83 @@
+package resources
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image/color"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func rawHeader(frame []byte, timestamp int64) []byte {
+	header := make([]byte, RawHeaderSize)
+	copy(header[0:4], RawMagic)
+	binary.BigEndian.PutUint32(header[4:8], 2)
+	binary.BigEndian.PutUint32(header[8:12], 1)
+	binary.BigEndian.PutUint32(header[12:16], RawFourCCYUYV)
+	binary.BigEndian.PutUint64(header[16:24], uint64(timestamp))
+	binary.BigEndian.PutUint32(header[24:28], uint32(len(frame)))
+	return header
+}
+
+func TestDecodeRawFrame(t *testing.T) {
+	frame := []byte{0, 128, 255, 128}
+	header := rawHeader(frame, 1676700000000000000)
+
+	img, frameTime, err := decodeRawFrame(bytes.NewReader(append(header, frame...)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if frameTime.UnixNano() != 1676700000000000000 {
+		t.Fatalf("unexpected frame time %s", frameTime)
+	}
+	if c := img.At(1, 0); c != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("expected white pixel, got %v", c)
+	}
+
+	copy(header[0:4], "JPEG")
+	if _, _, err := decodeRawFrame(bytes.NewReader(append(header, frame...))); err == nil {
+		t.Fatalf("expected error on wrong magic")
+	}
+}
+
+func TestWebcamSkipsSeenRawFrames(t *testing.T) {
+	// origin отдаёт кадры 1, 1, 1, 2, 2, 3, 3, ...
+	timestamps := []int64{1, 1, 1, 2, 2, 3}
+	var mutex sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		timestamp := timestamps[len(timestamps)-1]
+		if requests < len(timestamps) {
+			timestamp = timestamps[requests]
+		}
+		requests += 1
+		mutex.Unlock()
+		frame := []byte{byte(timestamp * 50), 128, byte(timestamp * 50), 128}
+		_, _ = w.Write(append(rawHeader(frame, timestamp), frame...))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	stream := NewWebcam(server.URL, true).GetStream(ctx)
+	for _, expected := range []uint8{1, 2, 3} {
+		select {
+		case img := <-stream:
+			if y := color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y; y/50 != expected {
+				t.Fatalf("expected frame %d, got frame with brightness %d", expected, y)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no frame %d", expected)
+		}
+	}
+	select {
+	case <-stream:
+		t.Fatalf("frame 3 must not be repeated")
+	case <-time.After(RetryMinDelay * 3):
+	}
+}